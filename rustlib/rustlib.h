@@ -17,14 +17,10 @@ struct CompilerOpts {
     uint8_t coverage_level;
 };
 
-struct LuaVmWrapper* newluavm();
-void luavm_setcompileropts(struct LuaVmWrapper* ptr, struct CompilerOpts opts);
-struct GoNoneResult luavm_setmemorylimit(struct LuaVmWrapper* ptr, size_t limit);
-void freeluavm(struct LuaVmWrapper* ptr);
-
 typedef void (*Callback)(void* val, uintptr_t handle);
 typedef void (*DropCallback)(uintptr_t handle);
 
+// Defined here as LuaVmOptions holds one
 struct IGoCallback {
     // Callback function pointer
     Callback callback;
@@ -34,6 +30,44 @@ struct IGoCallback {
     uintptr_t handle;
 };
 
+struct LuaVmOptions {
+    // Bitflags of the standard libraries to load
+    uint32_t stdlib;
+    // Called (with a null value) once the Lua VM is closed
+    struct IGoCallback on_close;
+};
+
+struct LuaVmWrapper* newluavm();
+struct GoLuaVmResult newluavm_with_options(struct LuaVmOptions opts);
+void luavm_setcompileropts(struct LuaVmWrapper* ptr, struct CompilerOpts opts);
+struct GoNoneResult luavm_setmemorylimit(struct LuaVmWrapper* ptr, size_t limit);
+struct LuaTable* luavm_globals(struct LuaVmWrapper* ptr);
+size_t luavm_used_memory(struct LuaVmWrapper* ptr);
+struct GoNoneResult luavm_gc_collect(struct LuaVmWrapper* ptr);
+struct GoBoolResult luavm_gc_step(struct LuaVmWrapper* ptr, int32_t kbytes);
+void luavm_gc_stop(struct LuaVmWrapper* ptr);
+void luavm_gc_restart(struct LuaVmWrapper* ptr);
+bool luavm_gc_is_running(struct LuaVmWrapper* ptr);
+struct GcIncParams {
+    // The GC goal (in percent), 0 to keep the current value
+    int32_t goal;
+    // The GC step multiplier (in percent), 0 to keep the current value
+    int32_t step_multiplier;
+    // The GC step size (in KB), 0 to keep the current value
+    int32_t step_size;
+};
+void luavm_gc_set_incremental(struct LuaVmWrapper* ptr, struct GcIncParams params);
+uint64_t luavm_interrupt_ticks(struct LuaVmWrapper* ptr);
+void freeluavm(struct LuaVmWrapper* ptr);
+
+// State of a call into Luau (its instruction budget and interrupt request)
+struct CallState;
+const struct CallState* luago_new_call(uint64_t budget);
+// Safe to call from any thread while the call is running
+void luago_call_interrupt(const struct CallState* ptr);
+uint64_t luago_call_ticks(const struct CallState* ptr);
+void luago_free_call(const struct CallState* ptr);
+
 // Test callbacks
 void test_callback(struct IGoCallback* cb, void* val);
 
@@ -101,6 +135,7 @@ struct GoLuaValue {
 };
 
 struct GoLuaValue luago_value_clone(struct GoLuaValue value);
+struct GoStringResult luago_value_to_string(struct LuaVmWrapper* ptr, struct GoLuaValue value);
 
 struct ErrorVariant* luago_error_new(const char* str, size_t len);
 struct LuaStringBytes luago_error_get_string(struct ErrorVariant* ptr);
@@ -165,9 +200,12 @@ struct FunctionCallbackData {
     // Go side may set this to set a response
     struct GoMultiValue* values; // NOTE: Rust will deallocate this
     struct ErrorVariant *error; // NOTE: Rust will deallocate this
+    struct IGoCallback go_error; // Holds the Go error, dropped once the GoError userdata is collected
 };
 struct GoFunctionResult luago_create_function(struct LuaVmWrapper* ptr, struct IGoCallback cb);
-struct GoMultiValueResult luago_function_call(struct LuaFunction* ptr, struct GoMultiValue* args);
+// On error, value may be set to hold the value the error was raised with
+struct GoMultiValueResult luago_function_call(struct LuaVmWrapper* lua, struct LuaFunction* ptr, struct GoMultiValue* args, const struct CallState* call);
+struct GoMultiValueResult luago_function_call_with_handler(struct LuaVmWrapper* lua, struct LuaFunction* ptr, struct LuaFunction* handler, struct GoMultiValue* args, const struct CallState* call);
 void luago_free_function(struct LuaFunction* f);
 
 // Userdata API
@@ -180,6 +218,44 @@ struct GoUserDataResult luago_create_userdata(struct LuaVmWrapper* ptr, struct D
 struct GoUsizePtrResult luago_get_userdata_handle(struct LuaUserData* ptr);
 void luago_free_userdata(struct LuaUserData* ptr);
 
+// Thread API
+struct LuaThread;
+struct GoThreadResult luago_create_thread(struct LuaVmWrapper* ptr, struct LuaFunction* f);
+// On error, value may be set to hold the value the error was raised with
+struct GoMultiValueResult luago_thread_resume(struct LuaVmWrapper* lua, struct LuaThread* ptr, struct GoMultiValue* args, const struct CallState* call);
+uint8_t luago_thread_status(struct LuaVmWrapper* lua, struct LuaThread* ptr);
+struct GoNoneResult luago_thread_reset(struct LuaThread* ptr, struct LuaFunction* f);
+uintptr_t luago_thread_to_pointer(struct LuaThread* ptr);
+void luago_free_thread(struct LuaThread* ptr);
+
+// Buffer API
+struct LuaBuffer;
+
+struct LuaBufferBytes {
+    // Pointer to the buffer data
+    char* data;
+    // Length of the buffer data
+    size_t len;
+};
+
+struct GoBufferResult luago_create_buffer(struct LuaVmWrapper* ptr, size_t size);
+struct GoBufferResult luago_create_buffer_from(struct LuaVmWrapper* ptr, const char* data, size_t len);
+struct LuaBufferBytes luago_buffer_as_bytes(struct LuaVmWrapper* lua, struct LuaBuffer* ptr);
+size_t luago_buffer_len(struct LuaBuffer* ptr);
+uintptr_t luago_buffer_to_pointer(struct LuaBuffer* ptr);
+void luago_free_buffer(struct LuaBuffer* ptr);
+
+// Registry API
+struct RegistryKey;
+struct GoRegistryKeyResult luago_create_registry_value(struct LuaVmWrapper* ptr, struct GoLuaValue value);
+struct GoValueResult luago_registry_value(struct LuaVmWrapper* ptr, struct RegistryKey* key);
+struct GoNoneResult luago_replace_registry_value(struct LuaVmWrapper* ptr, struct RegistryKey* key, struct GoLuaValue value);
+struct GoNoneResult luago_remove_registry_value(struct LuaVmWrapper* ptr, struct RegistryKey* key);
+struct GoNoneResult luago_set_named_registry_value(struct LuaVmWrapper* ptr, const char* name, size_t len, struct GoLuaValue value);
+struct GoValueResult luago_named_registry_value(struct LuaVmWrapper* ptr, const char* name, size_t len);
+struct GoNoneResult luago_unset_named_registry_value(struct LuaVmWrapper* ptr, const char* name, size_t len);
+void luago_free_registry_key(struct RegistryKey* key);
+
 // Result types
 
 struct GoNoneResult {
@@ -226,6 +302,32 @@ struct GoMultiValueResult {
     char* error;
 };
 
+struct GoLuaVmResult {
+    // Pointer to the LuaVmWrapper value
+    struct LuaVmWrapper* value;
+    // Pointer to a null-terminated C string for the error message
+    char* error;
+};
+
+struct GoThreadResult {
+    // Pointer to the LuaThread value
+    struct LuaThread* value;
+    // Pointer to a null-terminated C string for the error message
+    char* error;
+};
+struct GoBufferResult {
+    // Pointer to the LuaBuffer value
+    struct LuaBuffer* value;
+    // Pointer to a null-terminated C string for the error message
+    char* error;
+};
+struct GoRegistryKeyResult {
+    // Pointer to the RegistryKey value
+    struct RegistryKey* value;
+    // Pointer to a null-terminated C string for the error message
+    char* error;
+};
+
 struct GoValueResult {
     // The Lua value
     struct GoLuaValue value;
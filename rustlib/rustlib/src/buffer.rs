@@ -0,0 +1,118 @@
+//! Buffer related ops
+
+use crate::{error::encode_lua_error, result::GoBufferResult, LuaVmWrapper};
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_create_buffer(ptr: *mut LuaVmWrapper, size: usize) -> GoBufferResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return GoBufferResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    match lua.create_buffer_with_capacity(size) {
+        Ok(buf) => GoBufferResult::ok(Box::into_raw(Box::new(buf))),
+        Err(err) => GoBufferResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_create_buffer_from(ptr: *mut LuaVmWrapper, data: *const u8, len: usize) -> GoBufferResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    // and that data points to valid memory of length len.
+    if ptr.is_null() {
+        return GoBufferResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let res = if data.is_null() || len == 0 {
+        lua.create_buffer_with_capacity(0)
+    } else {
+        let slice = unsafe { std::slice::from_raw_parts(data, len) };
+        lua.create_buffer(slice)
+    };
+
+    match res {
+        Ok(buf) => GoBufferResult::ok(Box::into_raw(Box::new(buf))),
+        Err(err) => GoBufferResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[repr(C)]
+pub struct LuaBufferBytes {
+    // Pointer to the buffer data
+    pub data: *mut u8,
+    // Length of the buffer data
+    pub len: usize,
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_buffer_as_bytes(ptr: *mut LuaVmWrapper, buf: *mut mluau::Buffer) -> LuaBufferBytes {
+    let mut bytes = LuaBufferBytes {
+        data: std::ptr::null_mut(),
+        len: 0,
+    };
+
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    // and buf is a valid, non-null pointer to a Lua Buffer
+    if ptr.is_null() || buf.is_null() {
+        return bytes;
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let buf = unsafe { &*buf };
+
+    // Luau buffers are fixed size and are never moved by the GC, so the
+    // data pointer stays valid for as long as the buffer is alive.
+    //
+    // Note that to_pointer (lua_topointer) points to the buffer object
+    // itself, not its data, so lua_tobuffer must be used instead.
+    let res = unsafe {
+        lua.exec_raw::<()>(buf.clone(), |state| {
+            let mut len = 0;
+            let data = mluau::ffi::lua_tobuffer(state, -1, &mut len);
+            bytes.data = data as *mut u8;
+            bytes.len = len;
+        })
+    };
+    if res.is_err() {
+        return LuaBufferBytes {
+            data: std::ptr::null_mut(),
+            len: 0,
+        };
+    }
+    bytes
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_buffer_len(buf: *mut mluau::Buffer) -> usize {
+    // Safety: Assume buf is a valid, non-null pointer to a Lua Buffer
+    if buf.is_null() {
+        return 0;
+    }
+
+    let buf = unsafe { &*buf };
+    buf.len()
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_buffer_to_pointer(buf: *mut mluau::Buffer) -> usize {
+    // Safety: Assume buf is a valid, non-null pointer to a Lua Buffer
+    if buf.is_null() {
+        return 0;
+    }
+
+    let buf = unsafe { &*buf };
+    buf.to_pointer() as usize
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_free_buffer(buf: *mut mluau::Buffer) {
+    // Safety: Assume buf is a valid, non-null pointer to a Lua Buffer
+    if buf.is_null() {
+        return;
+    }
+
+    // Re-box the Lua Buffer pointer to manage its memory automatically.
+    unsafe { drop(Box::from_raw(buf)) };
+}
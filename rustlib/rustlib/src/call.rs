@@ -0,0 +1,116 @@
+use std::{cell::RefCell, sync::{atomic::{AtomicBool, AtomicU64, Ordering}, Arc}};
+
+use crate::InterruptState;
+
+/// State of a single call into Luau made from Go (a function call or
+/// thread resume), holding its instruction budget and interrupt request.
+///
+/// Luau code only runs on the OS thread that called into the Lua VM (Go
+/// callbacks and the calls they make stay on that thread), so the interrupt
+/// callback looks up the calls running on the current thread. This keeps the
+/// budget and interrupt of calls made concurrently from other threads apart.
+pub struct CallState {
+    // Set by Go (from any thread) to stop the call at the next interrupt point
+    interrupted: AtomicBool,
+    // Maximum number of interrupt ticks of the call (0 = unlimited).
+    // Only enforced for the outermost call, nested calls count towards it
+    budget: u64,
+    // Number of interrupt ticks counted while the call was running
+    ticks: AtomicU64,
+}
+
+thread_local! {
+    // Calls running on this thread (innermost last), along
+    // with the InterruptState of the Lua VM they run on
+    static CALLS: RefCell<Vec<(usize, Arc<CallState>)>> = const { RefCell::new(Vec::new()) };
+}
+
+/// Keeps a call registered as running on the current thread until dropped
+pub struct CallGuard;
+
+impl Drop for CallGuard {
+    fn drop(&mut self) {
+        CALLS.with(|calls| calls.borrow_mut().pop());
+    }
+}
+
+impl CallState {
+    /// Registers the call as running on the Lua VM of interrupt on the
+    /// current thread, until the returned guard is dropped
+    pub fn enter(self: &Arc<Self>, interrupt: &Arc<InterruptState>) -> CallGuard {
+        let key = Arc::as_ptr(interrupt) as usize;
+        CALLS.with(|calls| calls.borrow_mut().push((key, self.clone())));
+        CallGuard
+    }
+
+    /// Returns a new reference to the call state behind a pointer passed from Go
+    ///
+    /// Safety: ptr must have been returned by luago_new_call and not yet freed
+    pub unsafe fn from_ptr(ptr: *const CallState) -> Arc<CallState> {
+        unsafe {
+            Arc::increment_strong_count(ptr);
+            Arc::from_raw(ptr)
+        }
+    }
+}
+
+/// Called by the interrupt callback of a Lua VM, counting a tick for the
+/// calls running on it and stopping the running Luau code if the outermost
+/// call exceeded its budget or any of the calls was interrupted
+pub fn check_calls(interrupt: &InterruptState) -> mluau::Result<mluau::VmState> {
+    let key = interrupt as *const InterruptState as usize;
+    CALLS.with(|calls| {
+        let calls = calls.borrow();
+        let mut outermost = true;
+        for (_, call) in calls.iter().filter(|(k, _)| *k == key) {
+            let ticks = call.ticks.fetch_add(1, Ordering::Relaxed) + 1;
+            if call.interrupted.load(Ordering::Acquire) {
+                return Err(mluau::Error::runtime("interrupted"));
+            }
+            if outermost && call.budget != 0 && ticks >= call.budget {
+                return Err(mluau::Error::runtime("instruction budget exceeded"));
+            }
+            outermost = false;
+        }
+        Ok(mluau::VmState::Continue)
+    })
+}
+
+// Creates the state of a call with the given budget (0 = unlimited)
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_new_call(budget: u64) -> *const CallState {
+    Arc::into_raw(Arc::new(CallState {
+        interrupted: AtomicBool::new(false),
+        budget,
+        ticks: AtomicU64::new(0),
+    }))
+}
+
+// Requests that the call be stopped at the next interrupt point.
+// This may be called from any thread.
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_call_interrupt(ptr: *const CallState) {
+    if ptr.is_null() {
+        return; // no-op if pointer is null
+    }
+    let call = unsafe { &*ptr };
+    call.interrupted.store(true, Ordering::Release);
+}
+
+// Returns the number of interrupt ticks counted while the call was running
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_call_ticks(ptr: *const CallState) -> u64 {
+    if ptr.is_null() {
+        return 0;
+    }
+    let call = unsafe { &*ptr };
+    call.ticks.load(Ordering::Relaxed)
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_free_call(ptr: *const CallState) {
+    if ptr.is_null() {
+        return;
+    }
+    unsafe { drop(Arc::from_raw(ptr)) };
+}
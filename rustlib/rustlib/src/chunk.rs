@@ -1,4 +1,4 @@
-use crate::{compiler::CompilerOpts, result::GoFunctionResult, LuaVmWrapper};
+use crate::{compiler::CompilerOpts, error::encode_lua_error, result::GoFunctionResult, LuaVmWrapper};
 
 // A ChunkString will be deallocated by Rust directly.
 pub struct ChunkString {
@@ -68,6 +68,6 @@ pub extern "C-unwind" fn luago_load_chunk(ptr: *mut LuaVmWrapper, opts: ChunkOpt
 
     match chunk.into_function() {
         Ok(f) => GoFunctionResult::ok(Box::into_raw(Box::new(f))),
-        Err(err) => GoFunctionResult::err(format!("{err}"))
+        Err(err) => GoFunctionResult::err(encode_lua_error(&err))
     }
 }
\ No newline at end of file
@@ -0,0 +1,158 @@
+//! Structured error reporting
+//!
+//! Errors returned by mluau are sent to Go as strings (like all other errors),
+//! but encoded with their kind, location and traceback so that Go can
+//! turn them into a LuaError.
+//!
+//! Format: `\x1e<kind>\x1e<chunk>\x1e<line>\x1e<traceback>\x1e<go error>\x1e<message>`
+//! where `<go error>` is the handle of the go_error callback holding the
+//! original Go error of a Go callback (or 0 if none).
+
+use crate::{protect::ProtectedCall, IGoCallback, IGoCallbackWrapper};
+
+// Error kinds as sent to Go
+pub const ERROR_KIND_OTHER: u8 = 0;
+pub const ERROR_KIND_SYNTAX: u8 = 1;
+pub const ERROR_KIND_RUNTIME: u8 = 2;
+pub const ERROR_KIND_MEMORY: u8 = 3;
+pub const ERROR_KIND_CALLBACK: u8 = 4;
+pub const ERROR_KIND_CLOSED_OBJECT: u8 = 5;
+
+/// Separator of the fields of an encoded error
+pub const ERROR_FIELD_SEPARATOR: char = '\u{1e}';
+
+/// Encodes a mluau error for sending to Go
+pub fn encode_lua_error(err: &mluau::Error) -> String {
+    let (kind, message, traceback) = classify_error(err);
+    encode(kind, &message, &traceback, 0)
+}
+
+fn encode(kind: u8, message: &str, traceback: &str, go_error: u64) -> String {
+    let (chunk, line) = error_location(message);
+    let sep = ERROR_FIELD_SEPARATOR;
+    format!("{sep}{kind}{sep}{chunk}{sep}{line}{sep}{traceback}{sep}{go_error}{sep}{message}")
+}
+
+/// Returns the kind, message and traceback of an error
+fn classify_error(err: &mluau::Error) -> (u8, String, String) {
+    match err {
+        mluau::Error::SyntaxError { message, .. } => (ERROR_KIND_SYNTAX, message.clone(), String::new()),
+        mluau::Error::RuntimeError(msg) => {
+            let (message, traceback) = split_traceback(msg);
+            (ERROR_KIND_RUNTIME, message, traceback)
+        }
+        mluau::Error::MemoryError(msg) => (ERROR_KIND_MEMORY, msg.clone(), String::new()),
+        mluau::Error::CallbackError { traceback, cause } => {
+            let (kind, message, inner_traceback) = classify_error(cause);
+            // Errors that merely passed through a callback keep their kind
+            let kind = match kind {
+                ERROR_KIND_OTHER | ERROR_KIND_RUNTIME => ERROR_KIND_CALLBACK,
+                k => k,
+            };
+            let traceback = if traceback.is_empty() { inner_traceback } else { traceback.clone() };
+            (kind, message, traceback)
+        }
+        mluau::Error::WithContext { context, cause } => {
+            let (kind, message, traceback) = classify_error(cause);
+            (kind, format!("{context}: {message}"), traceback)
+        }
+        mluau::Error::UserDataDestructed => (ERROR_KIND_CLOSED_OBJECT, err.to_string(), String::new()),
+        _ => (ERROR_KIND_OTHER, err.to_string(), String::new()),
+    }
+}
+
+/// Splits the traceback appended to runtime errors from the error message
+fn split_traceback(msg: &str) -> (String, String) {
+    match msg.find("\nstack traceback:") {
+        Some(idx) => (msg[..idx].to_string(), msg[idx + 1..].to_string()),
+        None => (msg.to_string(), String::new()),
+    }
+}
+
+/// Returns the chunk name and line of an error message in
+/// the `chunk:line: message` format used by Luau (if any)
+fn error_location(message: &str) -> (String, u32) {
+    let first_line = message.lines().next().unwrap_or("");
+    let mut search = 0;
+    while let Some(idx) = first_line[search..].find(':') {
+        let start = search + idx;
+        let rest = &first_line[start + 1..];
+        let digits = rest.bytes().take_while(|b| b.is_ascii_digit()).count();
+        if digits > 0 && rest[digits..].starts_with(':') {
+            let chunk = &first_line[..start];
+            let chunk = chunk
+                .strip_prefix("[string \"")
+                .and_then(|c| c.strip_suffix("\"]"))
+                .unwrap_or(chunk);
+            return (chunk.to_string(), rest[..digits].parse().unwrap_or(0));
+        }
+        search = start + 1;
+    }
+    (String::new(), 0)
+}
+
+/// An error returned by a Go callback
+///
+/// Raised into Luau as userdata (so scripts can inspect `err.message`). The
+/// original Go error is owned by the Go side of the go_error callback and
+/// is released once the userdata is collected.
+///
+/// So that scripts treating errors as strings keep working, GoError
+/// supports `tostring`, concatenation and the string methods (which
+/// operate on the message, e.g. `err:match(...)`).
+pub struct GoError {
+    pub message: String,
+    go_error: IGoCallbackWrapper,
+}
+
+impl GoError {
+    pub fn new(message: String, go_error: IGoCallback) -> Self {
+        GoError { message, go_error: IGoCallbackWrapper::new(go_error) }
+    }
+}
+
+impl mluau::UserData for GoError {
+    fn add_methods<M: mluau::UserDataMethods<Self>>(methods: &mut M) {
+        methods.add_meta_method(mluau::MetaMethod::Index, |lua, this, key: mluau::Value| {
+            if let mluau::Value::String(k) = &key {
+                if k.as_bytes() == b"message".as_slice() {
+                    return Ok(mluau::Value::String(lua.create_string(&this.message)?));
+                }
+            }
+            ProtectedCall::get(lua)?.string_method(&this.message, key)
+        });
+        methods.add_meta_method(mluau::MetaMethod::ToString, |_, this, ()| Ok(this.message.clone()));
+        methods.add_meta_function(mluau::MetaMethod::Concat, |_, (a, b): (mluau::Value, mluau::Value)| {
+            Ok(format!("{}{}", a.to_string()?, b.to_string()?))
+        });
+        methods.add_meta_method(mluau::MetaMethod::Len, |_, this, ()| Ok(this.message.len()));
+    }
+}
+
+/// Encodes an error value raised by Luau code (as captured by
+/// a protected call) for sending to Go
+pub fn encode_error_value(value: &mluau::Value, traceback: String) -> String {
+    let (kind, message, traceback, go_error) = match value {
+        mluau::Value::Error(err) => {
+            let (kind, message, inner_traceback) = classify_error(err);
+            let traceback = if inner_traceback.is_empty() { traceback } else { inner_traceback };
+            (kind, message, traceback, 0)
+        }
+        mluau::Value::UserData(ud) => match ud.borrow::<GoError>() {
+            // The GoError must be kept alive until Go has taken the Go error
+            // (see CallError), as its handle is only valid until then
+            Ok(go_err) => (ERROR_KIND_CALLBACK, go_err.message.clone(), traceback, go_err.go_error.handle() as u64),
+            Err(_) => (ERROR_KIND_RUNTIME, value_message(value), traceback, 0),
+        },
+        _ => (ERROR_KIND_RUNTIME, value_message(value), traceback, 0),
+    };
+    encode(kind, &message, &traceback, go_error)
+}
+
+/// Returns the message of a non-error value raised by Luau code
+fn value_message(value: &mluau::Value) -> String {
+    match value.to_string() {
+        Ok(s) => s,
+        Err(_) => format!("({} error value)", value.type_name()),
+    }
+}
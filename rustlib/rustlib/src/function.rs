@@ -1,6 +1,6 @@
 use std::ffi::c_void;
 
-use crate::{multivalue::GoMultiValue, result::{GoFunctionResult, GoMultiValueResult}, value::ErrorVariant, IGoCallback, IGoCallbackWrapper, LuaVmWrapper};
+use crate::{call::CallState, error::{encode_lua_error, GoError}, protect::{CallError, ProtectedCall}, multivalue::GoMultiValue, result::{GoFunctionResult, GoMultiValueResult}, value::ErrorVariant, IGoCallback, IGoCallbackWrapper, LuaVmWrapper};
 
 #[repr(C)]
 // NOTE: Aside from the LuaVmWrapper, Rust will deallocate everything
@@ -17,6 +17,9 @@ pub struct FunctionCallbackData {
     // Go side may set this to set a response
     pub values: *mut GoMultiValue,
     pub error: *mut ErrorVariant,
+    // Go side may set this along with error to pass the original
+    // Go error (see GoError)
+    pub go_error: IGoCallback,
 }
 
 #[unsafe(no_mangle)]
@@ -29,8 +32,15 @@ pub extern "C-unwind" fn luago_create_function(ptr: *mut LuaVmWrapper, cb: IGoCa
     let cb_wrapper = IGoCallbackWrapper::new(cb);
 
     let lua = unsafe { &(*ptr).lua };
+    let pc = match ProtectedCall::get(lua) {
+        Ok(pc) => pc,
+        Err(err) => return GoFunctionResult::err(encode_lua_error(&err)),
+    };
+
+    // Errors are returned as `false, err` (and raised by the wrapper
+    // function) so that err can be a GoError instead of an mluau error
     let func = lua.create_function(move |lua, args: mluau::MultiValue| {
-        let wrapper = Box::new(LuaVmWrapper { lua: lua.clone() });
+        let wrapper = Box::new(LuaVmWrapper::from_lua(lua.clone()));
         let lua_ptr = Box::into_raw(wrapper);
         
         let data = FunctionCallbackData {
@@ -38,6 +48,7 @@ pub extern "C-unwind" fn luago_create_function(ptr: *mut LuaVmWrapper, cb: IGoCa
             args: GoMultiValue::inst(args),
             values: std::ptr::null_mut(),
             error: std::ptr::null_mut(),
+            go_error: IGoCallback::none(),
         };
 
         let ptr = Box::into_raw(Box::new(data));
@@ -52,30 +63,38 @@ pub extern "C-unwind" fn luago_create_function(ptr: *mut LuaVmWrapper, cb: IGoCa
             }
 
             let error = unsafe { Box::from_raw(data.error) };
-            return Err(mluau::Error::external(error.error.to_string_lossy()));
+            let go_error = GoError::new(error.error.to_string_lossy().into_owned(), data.go_error);
+            let mut rets = mluau::MultiValue::with_capacity(2);
+            rets.push_back(mluau::Value::Boolean(false));
+            rets.push_back(mluau::Value::UserData(lua.create_userdata(go_error)?));
+            return Ok(rets);
         } else {
             // If values is set, return them to Lua.
-            if !data.values.is_null() {
+            let mut rets = if !data.values.is_null() {
                 // Safety: Go side must ensure values cannot be used after it is set
                 // here as a return value
                 let values = unsafe { Box::from_raw(data.values) };
-                let values_mv = values.values.into_inner().unwrap();
-                return Ok(values_mv);
+                values.values.into_inner().unwrap()
             } else {
                 // If no values are set, return an empty MultiValue.
-                return Ok(mluau::MultiValue::new());
-            }
+                mluau::MultiValue::new()
+            };
+            rets.push_front(mluau::Value::Boolean(true));
+            return Ok(rets);
         }
-    });
+    }).and_then(|inner| pc.wrap_callback(inner));
 
     match func {
         Ok(f) => GoFunctionResult::ok(Box::into_raw(Box::new(f))),
-        Err(err) => GoFunctionResult::err(format!("{err}")),
+        Err(err) => GoFunctionResult::err(encode_lua_error(&err)),
     }
 }
 
 #[unsafe(no_mangle)]
-pub extern "C-unwind" fn luago_function_call(ptr: *mut mluau::Function, args: *mut GoMultiValue) -> GoMultiValueResult  {
+pub extern "C-unwind" fn luago_function_call(lua: *mut LuaVmWrapper, ptr: *mut mluau::Function, args: *mut GoMultiValue, call: *const CallState) -> GoMultiValueResult  {
+    if lua.is_null() || call.is_null() {
+        return GoMultiValueResult::err("LuaVmWrapper or CallState pointer is null".to_string());
+    }
     if ptr.is_null() {
         return GoMultiValueResult::err("Function pointer is null".to_string());
     }
@@ -86,10 +105,56 @@ pub extern "C-unwind" fn luago_function_call(ptr: *mut mluau::Function, args: *m
     // here as a return value
     let values = unsafe { Box::from_raw(args) };
     let values_mv = values.values.into_inner().unwrap();
-    let res = func.call::<mluau::MultiValue>(values_mv);
+
+    let call = unsafe { CallState::from_ptr(call) };
+    let _guard = call.enter(unsafe { &(*lua).interrupt });
+
+    let lua = unsafe { &(*lua).lua };
+    let res = ProtectedCall::get(lua)
+        .map_err(|e| CallError { error: encode_lua_error(&e), value: None })
+        .and_then(|pc| pc.call(func, values_mv));
+    call_result(res)
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_function_call_with_handler(lua: *mut LuaVmWrapper, ptr: *mut mluau::Function, handler: *mut mluau::Function, args: *mut GoMultiValue, call: *const CallState) -> GoMultiValueResult  {
+    if lua.is_null() || call.is_null() {
+        return GoMultiValueResult::err("LuaVmWrapper or CallState pointer is null".to_string());
+    }
+    if ptr.is_null() {
+        return GoMultiValueResult::err("Function pointer is null".to_string());
+    }
+    if handler.is_null() {
+        return GoMultiValueResult::err("Handler function pointer is null".to_string());
+    }
+
+    let call = unsafe { CallState::from_ptr(call) };
+    let _guard = call.enter(unsafe { &(*lua).interrupt });
+
+    let lua = unsafe { &(*lua).lua };
+    let func = unsafe { &*ptr };
+    let handler = unsafe { &*handler };
+
+    // Safety: Go side must ensure values cannot be used after it is set
+    // here as a return value
+    let values = unsafe { Box::from_raw(args) };
+    let values_mv = values.values.into_inner().unwrap();
+
+    let res = ProtectedCall::get(lua)
+        .map_err(|e| CallError { error: encode_lua_error(&e), value: None })
+        .and_then(|pc| pc.call_with_handler(func, handler, values_mv));
+    call_result(res)
+}
+
+/// Converts the result of a protected call, passing the raised
+/// error value (if any) to Go along with the error
+pub fn call_result(res: Result<mluau::MultiValue, CallError>) -> GoMultiValueResult {
     match res {
         Ok(mv) => GoMultiValueResult::ok(GoMultiValue::inst(mv)),
-        Err(e) => GoMultiValueResult::err(format!("{e}"))
+        Err(CallError { error, value: Some(value) }) => {
+            GoMultiValueResult::err_with_value(error, GoMultiValue::inst(mluau::MultiValue::from_vec(vec![value])))
+        }
+        Err(CallError { error, value: None }) => GoMultiValueResult::err(error),
     }
 }
 
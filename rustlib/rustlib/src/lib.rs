@@ -8,9 +8,15 @@ pub mod multivalue;
 pub mod compiler;
 pub mod chunk;
 pub mod userdata;
+pub mod registry;
+pub mod thread;
+pub mod buffer;
+pub mod error;
+pub mod protect;
+pub mod call;
 
 use mluau::Lua;
-use std::ffi::c_void;
+use std::{ffi::c_void, sync::{atomic::AtomicU64, Arc}};
 
 // typedef void (*Callback)(void* val, void* handle);
 // typedef void (*DropCallback)(void* handle);
@@ -24,6 +30,15 @@ pub struct IGoCallback {
     handle: usize,
 }
 
+impl IGoCallback {
+    /// Returns a callback that is never called (for optional callbacks)
+    pub fn none() -> Self {
+        extern "C" fn noop_callback(_val: *mut c_void, _handle: usize) {}
+        extern "C" fn noop_drop(_handle: usize) {}
+        IGoCallback { callback: noop_callback, drop: noop_drop, handle: 0 }
+    }
+}
+
 pub struct IGoCallbackWrapper {
     callback: IGoCallback,
 }
@@ -33,6 +48,11 @@ impl IGoCallbackWrapper {
         IGoCallbackWrapper { callback }
     }
 
+    /// Returns the Go handle of the callback
+    pub fn handle(&self) -> usize {
+        self.callback.handle
+    }
+
     pub fn callback(&self, val: *mut c_void) {
         // Ensure the callback function is valid before calling it.
         // This prevents dereferencing a null pointer or calling an invalid function.
@@ -64,4 +84,39 @@ pub extern "C-unwind" fn test_callback(cb: IGoCallback, val: *mut c_void) {
 
 pub struct LuaVmWrapper {
     pub lua: Lua,
+    // Kept outside of the Lua VM so that it can be accessed from
+    // other threads without locking the Lua VM
+    pub interrupt: Arc<InterruptState>,
+}
+
+impl LuaVmWrapper {
+    /// Creates a new LuaVmWrapper for an existing Lua VM
+    pub fn from_lua(lua: Lua) -> Self {
+        let interrupt = InterruptState::get(&lua).unwrap_or_else(InterruptState::new);
+        LuaVmWrapper { lua, interrupt }
+    }
+}
+
+/// State shared between the interrupt callback of a Lua VM and the Go side.
+///
+/// This is stored in the app data of the Lua VM so that every LuaVmWrapper
+/// (including the ones made for function callbacks) can access it.
+///
+/// The budget and interrupt requests of calls are kept per call (see call::CallState).
+pub struct InterruptState {
+    // Number of times the interrupt callback has been called
+    pub ticks: AtomicU64,
+}
+
+impl InterruptState {
+    pub fn new() -> Arc<Self> {
+        Arc::new(InterruptState {
+            ticks: AtomicU64::new(0),
+        })
+    }
+
+    /// Returns the InterruptState of a Lua VM
+    pub fn get(lua: &Lua) -> Option<Arc<Self>> {
+        lua.app_data_ref::<Arc<InterruptState>>().map(|s| s.clone())
+    }
 }
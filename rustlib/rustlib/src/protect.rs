@@ -0,0 +1,278 @@
+//! Protected calls from Go
+//!
+//! Functions called from Go are called using `xpcall` with a message handler
+//! that captures the raised error value (along with a traceback) so that
+//! non-string errors (such as the GoError userdata raised for errors
+//! returned by Go callbacks, or tables raised by scripts) reach Go intact.
+//!
+//! The functions used are taken when the VM is created (see ProtectedCall::init),
+//! so scripts replacing globals such as `xpcall` cannot interfere with them.
+
+use std::{ffi::CStr, sync::Arc};
+
+use mluau::{Function, Lua, MultiValue, Table, Thread, Value};
+
+use crate::error::{encode_error_value, encode_lua_error};
+
+const PROTECT_SOURCE: &str = r#"
+local traceback, thread_traceback = ...
+local error, setmetatable, type = error, setmetatable, type
+local string = string
+local resume = coroutine and coroutine.resume
+local Captured = {}
+
+local function check(ok, ...)
+	if not ok then
+		error((...), 0)
+	end
+	return ...
+end
+
+-- Wraps a Go callback (which returns false, err on error) to raise its errors
+local function wrap(inner)
+	return function(...)
+		return check(inner(...))
+	end
+end
+
+local function handler(err)
+	return setmetatable({ err, traceback(2) }, Captured)
+end
+
+-- Returns a handler capturing the error value returned by the message handler h
+local function decorate(h)
+	return function(err)
+		return setmetatable({ h(err), traceback(2) }, Captured)
+	end
+end
+
+-- Returns the string method k bound to message, so that GoErrors
+-- can be used like strings (err:match(...))
+local function string_method(message, k)
+	local f = string and string[k]
+	if type(f) ~= "function" then
+		return nil
+	end
+	return function(_, ...)
+		return f(message, ...)
+	end
+end
+
+local function finish_resume(co, ok, ...)
+	if ok then
+		return true, ...
+	end
+	return false, setmetatable({ (...), thread_traceback(co) }, Captured)
+end
+
+-- Resumes co, capturing the error value (and the traceback of co) on error
+local function resume_thread(co, ...)
+	return finish_resume(co, resume(co, ...))
+end
+
+return wrap, handler, decorate, string_method, resume and resume_thread, Captured
+"#;
+
+/// An error from a protected call
+pub struct CallError {
+    /// The encoded error
+    pub error: String,
+    /// The value raised by Luau code, if any
+    pub value: Option<Value>,
+}
+
+impl CallError {
+    fn encoded(error: String) -> Self {
+        CallError { error, value: None }
+    }
+
+    /// Returns the error for value being raised by Luau code
+    fn raised(value: Value, traceback: String) -> Self {
+        let error = encode_error_value(&value, traceback);
+        match value {
+            // Errors raised by mluau are not Luau values
+            Value::Error(_) => CallError::encoded(error),
+            value => CallError { error, value: Some(value) },
+        }
+    }
+}
+
+/// Per-VM state for protected calls, stored in the app data of the VM
+pub struct ProtectedCall {
+    xpcall: Function,
+    wrap: Function,
+    handler: Function,
+    decorate: Function,
+    string_method: Function,
+    resume: Option<Function>,
+    captured: Table,
+}
+
+impl ProtectedCall {
+    /// Creates the protected call state of a new VM. Must be called before
+    /// any other code runs on the VM
+    pub fn init(lua: &Lua) -> mluau::Result<()> {
+        let pc = Arc::new(ProtectedCall::new(lua)?);
+        lua.set_app_data(pc);
+        Ok(())
+    }
+
+    /// Returns the protected call state of a VM
+    pub fn get(lua: &Lua) -> mluau::Result<Arc<ProtectedCall>> {
+        match lua.app_data_ref::<Arc<ProtectedCall>>() {
+            Some(pc) => Ok(pc.clone()),
+            None => Err(mluau::Error::runtime("protected call state not initialized")),
+        }
+    }
+
+    fn new(lua: &Lua) -> mluau::Result<Self> {
+        let xpcall: Function = lua.globals().raw_get("xpcall")?;
+        let traceback = lua.create_function(|lua, level: usize| Ok(stack_traceback(lua, level)))?;
+        let thread_traceback = lua.create_function(|lua, thread: Thread| Ok(thread_traceback(lua, &thread)))?;
+        let (wrap, handler, decorate, string_method, resume, captured) = lua
+            .load(PROTECT_SOURCE)
+            .set_name("=gluau")
+            .call::<(Function, Function, Function, Function, Option<Function>, Table)>((traceback, thread_traceback))?;
+        Ok(ProtectedCall { xpcall, wrap, handler, decorate, string_method, resume, captured })
+    }
+
+    /// Wraps a function created for a Go callback so that the
+    /// `false, err` it returns on error is raised as err
+    pub fn wrap_callback(&self, inner: Function) -> mluau::Result<Function> {
+        self.wrap.call::<Function>(inner)
+    }
+
+    /// Returns the string method key bound to message (or nil), see GoError
+    pub fn string_method(&self, message: &str, key: Value) -> mluau::Result<Value> {
+        self.string_method.call::<Value>((message, key))
+    }
+
+    /// Calls func with args
+    pub fn call(&self, func: &Function, args: MultiValue) -> Result<MultiValue, CallError> {
+        self.xpcall(func, self.handler.clone(), args)
+    }
+
+    /// Calls func with args, calling the message handler with the error
+    /// value (at the point of the error) if func raises an error.
+    ///
+    /// The value returned by the handler becomes the error value.
+    pub fn call_with_handler(&self, func: &Function, handler: &Function, args: MultiValue) -> Result<MultiValue, CallError> {
+        let handler = self
+            .decorate
+            .call::<Function>(handler.clone())
+            .map_err(|e| CallError::encoded(encode_lua_error(&e)))?;
+        self.xpcall(func, handler, args)
+    }
+
+    fn xpcall(&self, func: &Function, handler: Function, args: MultiValue) -> Result<MultiValue, CallError> {
+        let mut call_args = MultiValue::with_capacity(args.len() + 2);
+        call_args.push_back(Value::Function(func.clone()));
+        call_args.push_back(Value::Function(handler));
+        call_args.extend(args);
+
+        let rets = self.xpcall.call::<MultiValue>(call_args).map_err(|e| CallError::encoded(encode_lua_error(&e)))?;
+        self.finish(rets)
+    }
+
+    /// Resumes thread with args
+    ///
+    /// Without the coroutine library, errors are returned without
+    /// the value they were raised with.
+    pub fn resume(&self, thread: &Thread, args: MultiValue) -> Result<MultiValue, CallError> {
+        let Some(resume) = &self.resume else {
+            return thread.resume::<MultiValue>(args).map_err(|e| CallError::encoded(encode_lua_error(&e)));
+        };
+
+        let mut call_args = MultiValue::with_capacity(args.len() + 1);
+        call_args.push_back(Value::Thread(thread.clone()));
+        call_args.extend(args);
+
+        let rets = resume.call::<MultiValue>(call_args).map_err(|e| CallError::encoded(encode_lua_error(&e)))?;
+        self.finish(rets)
+    }
+
+    /// Converts the `ok, ...` returned by xpcall (or resume_thread)
+    fn finish(&self, mut rets: MultiValue) -> Result<MultiValue, CallError> {
+        match rets.pop_front() {
+            Some(Value::Boolean(true)) => Ok(rets),
+            _ => Err(self.encode_error(rets.pop_front().unwrap_or(Value::Nil))),
+        }
+    }
+
+    /// Converts the error returned by xpcall
+    fn encode_error(&self, err: Value) -> CallError {
+        if let Value::Table(t) = &err {
+            if t.metatable().as_ref() == Some(&self.captured) {
+                let value: Value = t.raw_get(1).unwrap_or(Value::Nil);
+                let traceback = match t.raw_get::<Option<String>>(2) {
+                    Ok(Some(tb)) if !tb.is_empty() => format!("stack traceback:\n{}", tb.trim_end()),
+                    _ => String::new(),
+                };
+                return CallError::raised(value, traceback);
+            }
+        }
+
+        // The message handler was not run (out of memory or an
+        // error in the handler itself)
+        if let Value::String(s) = &err {
+            if s.to_string_lossy() == "not enough memory" {
+                return CallError::encoded(encode_lua_error(&mluau::Error::MemoryError(s.to_string_lossy())));
+            }
+        }
+        CallError::raised(err, String::new())
+    }
+}
+
+/// Returns a traceback of the stack of thread (which has errored or yielded)
+///
+/// Like stack_traceback, this works without the debug library loaded.
+fn thread_traceback(lua: &Lua, thread: &Thread) -> String {
+    let mut traceback = String::new();
+    let _ = unsafe {
+        lua.exec_raw::<()>(thread.clone(), |state| {
+            let co = mluau::ffi::lua_tothread(state, -1);
+            let trace = mluau::ffi::lua_debugtrace(co);
+            if !trace.is_null() {
+                traceback = CStr::from_ptr(trace).to_string_lossy().into_owned();
+            }
+        })
+    };
+    traceback
+}
+
+/// Returns a traceback of the Luau stack starting at level, in
+/// the same format as debug.traceback
+///
+/// Unlike debug.traceback, this works without the debug library loaded.
+/// Frames of PROTECT_SOURCE are left out.
+fn stack_traceback(lua: &Lua, mut level: usize) -> String {
+    let mut traceback = String::new();
+    loop {
+        // None only once past the end of the stack: frames of PROTECT_SOURCE
+        // (such as the wrap closures calling Go callbacks) can be anywhere in
+        // the stack, so they are skipped rather than ending the traceback
+        let Some(frame) = lua.inspect_stack(level, |debug| {
+            let source = debug.source();
+            if source.source.as_deref() == Some("=gluau") {
+                return None;
+            }
+            let mut frame = source.short_src.as_deref().unwrap_or("?").to_string();
+            if let Some(line) = debug.current_line() {
+                frame.push_str(&format!(":{line}"));
+            }
+            if let Some(name) = debug.names().name {
+                frame.push_str(&format!(" function {name}"));
+            }
+            Some(frame)
+        }) else {
+            break;
+        };
+        level += 1;
+
+        if let Some(frame) = frame {
+            traceback.push_str(&frame);
+            traceback.push('\n');
+        }
+    }
+    traceback
+}
@@ -0,0 +1,130 @@
+//! Registry related ops
+
+use std::ffi::c_char;
+
+use crate::{error::encode_lua_error, result::{GoNoneResult, GoRegistryKeyResult, GoValueResult}, value::GoLuaValue, LuaVmWrapper};
+
+fn registry_name(name: *const c_char, len: usize) -> String {
+    if name.is_null() {
+        return String::new();
+    }
+    let slice = unsafe { std::slice::from_raw_parts(name as *const u8, len) };
+    String::from_utf8_lossy(slice).into_owned()
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_create_registry_value(ptr: *mut LuaVmWrapper, value: GoLuaValue) -> GoRegistryKeyResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return GoRegistryKeyResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let value = value.to_value_from_owned();
+    match lua.create_registry_value(value) {
+        Ok(key) => GoRegistryKeyResult::ok(Box::into_raw(Box::new(key))),
+        Err(err) => GoRegistryKeyResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_registry_value(ptr: *mut LuaVmWrapper, key: *mut mluau::RegistryKey) -> GoValueResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() || key.is_null() {
+        return GoValueResult::err("LuaVmWrapper or RegistryKey pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let key = unsafe { &*key };
+    match lua.registry_value::<mluau::Value>(key) {
+        Ok(v) => GoValueResult::ok(GoLuaValue::from_owned(v)),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_replace_registry_value(ptr: *mut LuaVmWrapper, key: *mut mluau::RegistryKey, value: GoLuaValue) -> GoNoneResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() || key.is_null() {
+        return GoNoneResult::err("LuaVmWrapper or RegistryKey pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let key = unsafe { &mut *key };
+    let value = value.to_value_from_owned();
+    match lua.replace_registry_value(key, value) {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+// Removes the value from the registry, taking ownership of (and freeing) the key
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_remove_registry_value(ptr: *mut LuaVmWrapper, key: *mut mluau::RegistryKey) -> GoNoneResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() || key.is_null() {
+        return GoNoneResult::err("LuaVmWrapper or RegistryKey pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let key = unsafe { Box::from_raw(key) };
+    match lua.remove_registry_value(*key) {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_set_named_registry_value(ptr: *mut LuaVmWrapper, name: *const c_char, len: usize, value: GoLuaValue) -> GoNoneResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return GoNoneResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let value = value.to_value_from_owned();
+    match lua.set_named_registry_value(&registry_name(name, len), value) {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_named_registry_value(ptr: *mut LuaVmWrapper, name: *const c_char, len: usize) -> GoValueResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return GoValueResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    match lua.named_registry_value::<mluau::Value>(&registry_name(name, len)) {
+        Ok(v) => GoValueResult::ok(GoLuaValue::from_owned(v)),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_unset_named_registry_value(ptr: *mut LuaVmWrapper, name: *const c_char, len: usize) -> GoNoneResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return GoNoneResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    match lua.unset_named_registry_value(&registry_name(name, len)) {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_free_registry_key(key: *mut mluau::RegistryKey) {
+    // Safety: Assume key is a valid, non-null pointer to a RegistryKey
+    if key.is_null() {
+        return;
+    }
+
+    // Dropping a RegistryKey without removing it marks the value as expired,
+    // mluau will clean it up on the next registry operation.
+    unsafe { drop(Box::from_raw(key)) };
+}
@@ -1,6 +1,6 @@
 use std::ffi::{c_char, CString};
 
-use crate::{multivalue::GoMultiValue, value::GoLuaValue};
+use crate::{multivalue::GoMultiValue, value::GoLuaValue, LuaVmWrapper};
 
 #[repr(C)]
 pub struct GoNoneResult {
@@ -213,6 +213,103 @@ impl GoMultiValueResult {
             error: to_error(error),
         }
     }
+
+    /// Like err, additionally passing the error value raised by Luau
+    /// (as the only value of value)
+    pub fn err_with_value(error: String, value: *mut GoMultiValue) -> Self {
+        Self {
+            value,
+            error: to_error(error),
+        }
+    }
+}
+
+#[repr(C)]
+pub struct GoLuaVmResult {
+    value: *mut LuaVmWrapper,
+    error: *mut c_char
+}
+
+impl GoLuaVmResult {
+    pub fn ok(v: *mut LuaVmWrapper) -> Self {
+        Self {
+            value: v,
+            error: std::ptr::null_mut(),
+        }
+    }
+
+    pub fn err(error: String) -> Self {
+        Self {
+            value: std::ptr::null_mut(),
+            error: to_error(error),
+        }
+    }
+}
+
+#[repr(C)]
+pub struct GoThreadResult {
+    value: *mut mluau::Thread,
+    error: *mut c_char
+}
+
+impl GoThreadResult {
+    pub fn ok(t: *mut mluau::Thread) -> Self {
+        Self {
+            value: t,
+            error: std::ptr::null_mut(),
+        }
+    }
+
+    pub fn err(error: String) -> Self {
+        Self {
+            value: std::ptr::null_mut(),
+            error: to_error(error),
+        }
+    }
+}
+
+#[repr(C)]
+pub struct GoBufferResult {
+    value: *mut mluau::Buffer,
+    error: *mut c_char
+}
+
+impl GoBufferResult {
+    pub fn ok(b: *mut mluau::Buffer) -> Self {
+        Self {
+            value: b,
+            error: std::ptr::null_mut(),
+        }
+    }
+
+    pub fn err(error: String) -> Self {
+        Self {
+            value: std::ptr::null_mut(),
+            error: to_error(error),
+        }
+    }
+}
+
+#[repr(C)]
+pub struct GoRegistryKeyResult {
+    value: *mut mluau::RegistryKey,
+    error: *mut c_char
+}
+
+impl GoRegistryKeyResult {
+    pub fn ok(k: *mut mluau::RegistryKey) -> Self {
+        Self {
+            value: k,
+            error: std::ptr::null_mut(),
+        }
+    }
+
+    pub fn err(error: String) -> Self {
+        Self {
+            value: std::ptr::null_mut(),
+            error: to_error(error),
+        }
+    }
 }
 
 #[repr(C)]
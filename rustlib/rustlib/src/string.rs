@@ -2,7 +2,7 @@
 
 use std::ffi::c_char;
 
-use crate::{result::GoStringResult, LuaVmWrapper};
+use crate::{error::encode_lua_error, result::GoStringResult, LuaVmWrapper};
 
 #[unsafe(no_mangle)]
 pub extern "C-unwind" fn luago_create_string(ptr: *mut LuaVmWrapper, s: *const c_char, len: usize) -> GoStringResult  {
@@ -20,7 +20,7 @@ pub extern "C-unwind" fn luago_create_string(ptr: *mut LuaVmWrapper, s: *const c
 
     match res {
         Ok(str) => GoStringResult::ok(Box::into_raw(Box::new(str))),
-        Err(err) => GoStringResult::err(format!("{err}"))
+        Err(err) => GoStringResult::err(encode_lua_error(&err))
     }
 }
 
@@ -1,6 +1,6 @@
 use std::ffi::{c_char, c_void, CString};
 
-use crate::{result::{GoBoolResult, GoI64Result, GoNoneResult, GoTableResult, GoValueResult}, value::GoLuaValue, IGoCallback, IGoCallbackWrapper, LuaVmWrapper};
+use crate::{error::encode_lua_error, result::{GoBoolResult, GoI64Result, GoNoneResult, GoTableResult, GoValueResult}, value::GoLuaValue, IGoCallback, IGoCallbackWrapper, LuaVmWrapper};
 
 #[unsafe(no_mangle)]
 pub extern "C-unwind" fn luago_create_table(ptr: *mut LuaVmWrapper) -> GoTableResult  {
@@ -16,7 +16,7 @@ pub extern "C-unwind" fn luago_create_table(ptr: *mut LuaVmWrapper) -> GoTableRe
 
     match res {
         Ok(r) => GoTableResult::ok(Box::into_raw(Box::new(r))),
-        Err(err) => GoTableResult::err(format!("{err}")),
+        Err(err) => GoTableResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -34,7 +34,7 @@ pub extern "C-unwind" fn luago_create_table_with_capacity(ptr: *mut LuaVmWrapper
 
     match res {
         Ok(r) => GoTableResult::ok(Box::into_raw(Box::new(r))),
-        Err(err) => GoTableResult::err(format!("{err}")),
+        Err(err) => GoTableResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -51,7 +51,7 @@ pub extern "C-unwind" fn luago_table_clear(tab: *mut mluau::Table) -> GoNoneResu
 
     match res {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -69,7 +69,7 @@ pub extern "C-unwind" fn luago_table_contains_key(tab: *mut mluau::Table, value:
 
     match res {
         Ok(r) => GoBoolResult::ok(r),
-        Err(err) => GoBoolResult::err(format!("{err}")),
+        Err(err) => GoBoolResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -87,7 +87,7 @@ pub extern "C-unwind" fn luago_table_equals(tab: *mut mluau::Table, tab2: *mut m
 
     match res {
         Ok(r) => GoBoolResult::ok(r),
-        Err(err) => GoBoolResult::err(format!("{err}")),
+        Err(err) => GoBoolResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -137,7 +137,7 @@ pub extern "C-unwind" fn luago_table_foreach(tab: *mut mluau::Table, cb: IGoCall
             if stopped {
                 return GoNoneResult::ok(); // If stopped, return ok
             }
-            GoNoneResult::err(format!("{err}"))
+            GoNoneResult::err(encode_lua_error(&err))
         },
     }
 }
@@ -160,6 +160,7 @@ pub extern "C-unwind" fn luago_table_foreach_value(tab: *mut mluau::Table, cb: I
     let tab = unsafe { &*tab };
     let cb_wrapper = IGoCallbackWrapper::new(cb);
 
+    let mut stopped = false;
     let res = tab.for_each_value(|value: mluau::Value| {
         let data = TableForEachValueCallbackData {
             value: GoLuaValue::from_owned(value),
@@ -172,7 +173,8 @@ pub extern "C-unwind" fn luago_table_foreach_value(tab: *mut mluau::Table, cb: I
 
         if data.stop {
             // Use a dummy error variant to stop the iteration
-            return Err(mluau::Error::external("stop"));
+            stopped = true;
+            return Err(mluau::Error::external(""));
         }
 
         Ok(())
@@ -180,7 +182,12 @@ pub extern "C-unwind" fn luago_table_foreach_value(tab: *mut mluau::Table, cb: I
 
     match res {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => {
+            if stopped {
+                return GoNoneResult::ok(); // If stopped, return ok
+            }
+            GoNoneResult::err(encode_lua_error(&err))
+        },
     }
 }
 
@@ -197,7 +204,7 @@ pub extern "C-unwind" fn luago_table_get(tab: *mut mluau::Table, key: GoLuaValue
     
     match res {
         Ok(r) => GoValueResult::ok(GoLuaValue::from_owned(r)),
-        Err(err) => GoValueResult::err(format!("{err}")),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -233,7 +240,7 @@ pub extern "C-unwind" fn luago_table_len(tab: *mut mluau::Table) -> GoI64Result
     let tab = unsafe { &*tab };
     match tab.len() {
         Ok(len) => GoI64Result::ok(len),
-        Err(err) => GoI64Result::err(format!("{err}")),
+        Err(err) => GoI64Result::err(encode_lua_error(&err)),
     }
 }
 
@@ -261,7 +268,7 @@ pub extern "C-unwind" fn luago_table_pop(tab: *mut mluau::Table) -> GoValueResul
     let tab = unsafe { &*tab };
     match tab.pop::<mluau::Value>() {
         Ok(v) => GoValueResult::ok(GoLuaValue::from_owned(v)),
-        Err(err) => GoValueResult::err(format!("{err}")),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -275,7 +282,7 @@ pub extern "C-unwind" fn luago_table_push(tab: *mut mluau::Table, value: GoLuaVa
     let tab = unsafe { &*tab };
     match tab.push(value.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -289,7 +296,7 @@ pub extern "C-unwind" fn luago_table_raw_get(tab: *mut mluau::Table, key: GoLuaV
     let tab = unsafe { &*tab };
     match tab.raw_get::<mluau::Value>(key.to_value_from_owned()) {
         Ok(v) => GoValueResult::ok(GoLuaValue::from_owned(v)),
-        Err(err) => GoValueResult::err(format!("{err}")),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -303,7 +310,7 @@ pub extern "C-unwind" fn luago_table_raw_insert(tab: *mut mluau::Table, idx: i64
     let tab = unsafe { &*tab };
     match tab.raw_insert(idx, value.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -328,7 +335,7 @@ pub extern "C-unwind" fn luago_table_raw_pop(tab: *mut mluau::Table) -> GoValueR
     let tab = unsafe { &*tab };
     match tab.raw_pop::<mluau::Value>() {
         Ok(v) => GoValueResult::ok(GoLuaValue::from_owned(v)),
-        Err(err) => GoValueResult::err(format!("{err}")),
+        Err(err) => GoValueResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -342,7 +349,7 @@ pub extern "C-unwind" fn luago_table_raw_push(tab: *mut mluau::Table, value: GoL
     let tab = unsafe { &*tab };
     match tab.raw_push(value.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -356,7 +363,7 @@ pub extern "C-unwind" fn luago_table_raw_remove(tab: *mut mluau::Table, key: GoL
     let tab = unsafe { &*tab };
     match tab.raw_remove(key.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -370,7 +377,7 @@ pub extern "C-unwind" fn luago_table_raw_set(tab: *mut mluau::Table, key: GoLuaV
     let tab = unsafe { &*tab };
     match tab.raw_set(key.to_value_from_owned(), value.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -384,7 +391,7 @@ pub extern "C-unwind" fn luago_table_set(tab: *mut mluau::Table, key: GoLuaValue
     let tab = unsafe { &*tab };
     match tab.set(key.to_value_from_owned(), value.to_value_from_owned()) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
@@ -407,7 +414,7 @@ pub extern "C-unwind" fn luago_table_set_metatable(tab: *mut mluau::Table, metat
 
     match tab.set_metatable(metatable) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
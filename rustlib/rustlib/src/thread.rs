@@ -0,0 +1,109 @@
+//! Thread (coroutine) related ops
+
+use crate::{call::CallState, error::encode_lua_error, function::call_result, multivalue::GoMultiValue, protect::{CallError, ProtectedCall}, result::{GoMultiValueResult, GoNoneResult, GoThreadResult}, LuaVmWrapper};
+
+// Thread statuses as sent to Go
+pub const THREAD_STATUS_RESUMABLE: u8 = 0;
+pub const THREAD_STATUS_RUNNING: u8 = 1;
+pub const THREAD_STATUS_NORMAL: u8 = 2;
+pub const THREAD_STATUS_FINISHED: u8 = 3;
+pub const THREAD_STATUS_ERROR: u8 = 4;
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_create_thread(ptr: *mut LuaVmWrapper, func: *mut mluau::Function) -> GoThreadResult {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() || func.is_null() {
+        return GoThreadResult::err("LuaVmWrapper or Function pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let func = unsafe { &*func };
+    match lua.create_thread(func.clone()) {
+        Ok(t) => GoThreadResult::ok(Box::into_raw(Box::new(t))),
+        Err(err) => GoThreadResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_thread_resume(lua: *mut LuaVmWrapper, ptr: *mut mluau::Thread, args: *mut GoMultiValue, call: *const CallState) -> GoMultiValueResult {
+    if lua.is_null() || ptr.is_null() || call.is_null() {
+        return GoMultiValueResult::err("LuaVmWrapper, Thread or CallState pointer is null".to_string());
+    }
+
+    let thread = unsafe { &*ptr };
+
+    // Safety: Go side must ensure values cannot be used after it is set
+    // here as a return value
+    let values = unsafe { Box::from_raw(args) };
+    let values_mv = values.values.into_inner().unwrap();
+
+    let call = unsafe { CallState::from_ptr(call) };
+    let _guard = call.enter(unsafe { &(*lua).interrupt });
+
+    let lua = unsafe { &(*lua).lua };
+    let res = ProtectedCall::get(lua)
+        .map_err(|e| CallError { error: encode_lua_error(&e), value: None })
+        .and_then(|pc| pc.resume(thread, values_mv));
+    call_result(res)
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_thread_status(lua: *mut LuaVmWrapper, ptr: *mut mluau::Thread) -> u8 {
+    if lua.is_null() || ptr.is_null() {
+        return THREAD_STATUS_ERROR;
+    }
+
+    let lua = unsafe { &(*lua).lua };
+    let thread = unsafe { &*ptr };
+    match thread.status() {
+        mluau::ThreadStatus::Resumable => THREAD_STATUS_RESUMABLE,
+        mluau::ThreadStatus::Running => {
+            // A thread that is running but is not the current thread
+            // has resumed another thread (and is waiting for it)
+            let current = lua.current_thread();
+            if current.to_pointer() == thread.to_pointer() {
+                THREAD_STATUS_RUNNING
+            } else {
+                THREAD_STATUS_NORMAL
+            }
+        }
+        mluau::ThreadStatus::Finished => THREAD_STATUS_FINISHED,
+        mluau::ThreadStatus::Error => THREAD_STATUS_ERROR,
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_thread_reset(ptr: *mut mluau::Thread, func: *mut mluau::Function) -> GoNoneResult {
+    if ptr.is_null() || func.is_null() {
+        return GoNoneResult::err("Thread or Function pointer is null".to_string());
+    }
+
+    let thread = unsafe { &*ptr };
+    let func = unsafe { &*func };
+    match thread.reset(func.clone()) {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_thread_to_pointer(ptr: *mut mluau::Thread) -> usize {
+    // Safety: Assume ptr is a valid, non-null pointer to a Lua Thread
+    if ptr.is_null() {
+        return 0;
+    }
+
+    let thread = unsafe { &*ptr };
+    thread.to_pointer() as usize
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_free_thread(ptr: *mut mluau::Thread) {
+    // Safety: Assume ptr is a valid, non-null pointer to a Lua Thread
+    if ptr.is_null() {
+        return;
+    }
+
+    // Re-box the Lua Thread pointer to manage its memory automatically.
+    unsafe { drop(Box::from_raw(ptr)) };
+}
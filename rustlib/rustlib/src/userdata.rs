@@ -1,4 +1,4 @@
-use crate::{result::{GoAnyUserDataResult, GoUsizePtrResult}, LuaVmWrapper};
+use crate::{error::encode_lua_error, result::{GoAnyUserDataResult, GoUsizePtrResult}, LuaVmWrapper};
 
 /// DynamicData stores the cgo handle + callback for dynamic userdata functions.
 #[repr(C)]
@@ -33,7 +33,7 @@ pub extern "C-unwind" fn luago_create_userdata(ptr: *mut LuaVmWrapper, data: Dyn
 
     match res {
         Ok(userdata) => GoAnyUserDataResult::ok(Box::into_raw(Box::new(userdata))),
-        Err(e) => GoAnyUserDataResult::err(e.to_string()),
+        Err(e) => GoAnyUserDataResult::err(encode_lua_error(&e)),
     }
 }
 
@@ -47,7 +47,7 @@ pub extern "C-unwind" fn luago_get_userdata_handle(ud: *mut mluau::AnyUserData)
     let userdata = unsafe { &*ud };
     match userdata.dynamic_data::<DynamicData>() {
         Ok(data) => GoUsizePtrResult::ok(data.handle),
-        Err(e) => GoUsizePtrResult::err(e.to_string()),
+        Err(e) => GoUsizePtrResult::err(encode_lua_error(&e)),
     }
 }
 
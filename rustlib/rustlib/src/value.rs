@@ -1,6 +1,6 @@
 use std::{ffi::{c_void, CString}, sync::Arc};
 
-use crate::{string::LuaStringBytes, LuaVmWrapper};
+use crate::{error::encode_lua_error, result::GoStringResult, string::LuaStringBytes, LuaVmWrapper};
 
 #[repr(C)]
 pub enum LuaValueType {
@@ -292,6 +292,23 @@ pub extern "C-unwind" fn luago_value_clone(value: GoLuaValue) -> GoLuaValue {
     cloned_value
 }
 
+// Converts a value to a string the same way Luau's tostring does
+// (including calling the __tostring metamethod)
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luago_value_to_string(ptr: *mut LuaVmWrapper, value: GoLuaValue) -> GoStringResult {
+    if ptr.is_null() {
+        return GoStringResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+
+    let lua = unsafe { &(*ptr).lua };
+    let value = value.to_value_from_owned();
+    let res = value.to_string().and_then(|s| lua.create_string(s));
+    match res {
+        Ok(s) => GoStringResult::ok(Box::into_raw(Box::new(s))),
+        Err(err) => GoStringResult::err(encode_lua_error(&err)),
+    }
+}
+
 // Creates a new error variant given char array and length
 #[unsafe(no_mangle)]
 pub extern "C-unwind" fn luago_error_new(error: *const i8, len: usize) -> *mut ErrorVariant {
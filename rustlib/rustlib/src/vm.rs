@@ -1,24 +1,101 @@
+use std::sync::atomic::Ordering;
+
 use mluau::Lua;
 
-use crate::{compiler::CompilerOpts, result::GoNoneResult, LuaVmWrapper};
+use crate::{call, compiler::CompilerOpts, protect::ProtectedCall, error::encode_lua_error, result::{GoBoolResult, GoLuaVmResult, GoNoneResult}, IGoCallback, IGoCallbackWrapper, InterruptState, LuaVmWrapper};
 
-// Base functions
+// Standard library bitflags as sent by Go
+//
+// These are kept independent of mluau's own StdLib bit layout
+// so that the Go side does not break if mluau changes its layout.
+pub const STDLIB_COROUTINE: u32 = 1 << 0;
+pub const STDLIB_TABLE: u32 = 1 << 1;
+pub const STDLIB_OS: u32 = 1 << 2;
+pub const STDLIB_STRING: u32 = 1 << 3;
+pub const STDLIB_UTF8: u32 = 1 << 4;
+pub const STDLIB_BIT32: u32 = 1 << 5;
+pub const STDLIB_MATH: u32 = 1 << 6;
+pub const STDLIB_BUFFER: u32 = 1 << 7;
+pub const STDLIB_VECTOR: u32 = 1 << 8;
+pub const STDLIB_DEBUG: u32 = 1 << 9;
 
-#[unsafe(no_mangle)]
-pub extern "C-unwind" fn newluavm() -> *mut LuaVmWrapper {
+#[repr(C)]
+pub struct LuaVmOptions {
+    // Bitflags of the standard libraries to load
+    pub stdlib: u32,
+    // Called (with a null value) once the Lua VM is closed
+    pub on_close: IGoCallback,
+}
+
+impl LuaVmOptions {
+    pub fn to_stdlib(&self) -> mluau::StdLib {
+        let mut libs = mluau::StdLib::NONE;
+        let mapping = [
+            (STDLIB_COROUTINE, mluau::StdLib::COROUTINE),
+            (STDLIB_TABLE, mluau::StdLib::TABLE),
+            (STDLIB_OS, mluau::StdLib::OS),
+            (STDLIB_STRING, mluau::StdLib::STRING),
+            (STDLIB_UTF8, mluau::StdLib::UTF8),
+            (STDLIB_BIT32, mluau::StdLib::BIT),
+            (STDLIB_MATH, mluau::StdLib::MATH),
+            (STDLIB_BUFFER, mluau::StdLib::BUFFER),
+            (STDLIB_VECTOR, mluau::StdLib::VECTOR),
+            (STDLIB_DEBUG, mluau::StdLib::DEBUG),
+        ];
+        for (bit, lib) in mapping {
+            if self.stdlib & bit != 0 {
+                libs |= lib;
+            }
+        }
+        libs
+    }
+}
+
+fn create_vm(libs: mluau::StdLib, on_close: Option<IGoCallbackWrapper>) -> Result<*mut LuaVmWrapper, mluau::Error> {
     let lua = Lua::new_with(
-        mluau::StdLib::ALL_SAFE, // TODO: Allow configuring this
+        libs,
         mluau::LuaOptions::new()
         .catch_rust_panics(false)
         .disable_error_userdata(true)
-    ).unwrap(); // Will never error, as we are using safe libraries only.
+    )?;
 
-    lua.set_on_close(|| {
-        println!("Lua VM is being closed");
+    if let Some(on_close) = on_close {
+        lua.set_on_close(move || {
+            on_close.callback(std::ptr::null_mut());
+        });
+    }
+
+    let interrupt = InterruptState::new();
+    lua.set_app_data(interrupt.clone());
+    let state = interrupt.clone();
+    lua.set_interrupt(move |_| {
+        state.ticks.fetch_add(1, Ordering::Relaxed);
+        call::check_calls(&state)
     });
 
-    let wrapper = Box::new(LuaVmWrapper { lua });
-    Box::into_raw(wrapper)
+    // Before any script can replace the globals it uses
+    ProtectedCall::init(&lua)?;
+
+    let wrapper = Box::new(LuaVmWrapper { lua, interrupt });
+    Ok(Box::into_raw(wrapper))
+}
+
+// Base functions
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn newluavm() -> *mut LuaVmWrapper {
+    create_vm(mluau::StdLib::ALL_SAFE, None).unwrap() // Will never error, as we are using safe libraries only.
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn newluavm_with_options(opts: LuaVmOptions) -> GoLuaVmResult {
+    let libs = opts.to_stdlib();
+    // Wrap the callback first so that it is dropped even if creating the VM fails
+    let on_close = IGoCallbackWrapper::new(opts.on_close);
+    match create_vm(libs, Some(on_close)) {
+        Ok(ptr) => GoLuaVmResult::ok(ptr),
+        Err(err) => GoLuaVmResult::err(encode_lua_error(&err)),
+    }
 }
 
 #[unsafe(no_mangle)]
@@ -39,10 +116,125 @@ pub extern "C-unwind" fn luavm_setmemorylimit(ptr: *mut LuaVmWrapper, limit: usi
     let lua = unsafe { &(*ptr).lua };
     match lua.set_memory_limit(limit) {
         Ok(_) => GoNoneResult::ok(),
-        Err(err) => GoNoneResult::err(format!("{err}")),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
     }
 }
 
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_globals(ptr: *mut LuaVmWrapper) -> *mut mluau::Table {
+    // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
+    if ptr.is_null() {
+        return std::ptr::null_mut();
+    }
+    let lua = unsafe { &(*ptr).lua };
+    Box::into_raw(Box::new(lua.globals()))
+}
+
+// Returns the amount of memory (in bytes) currently used by the Lua VM
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_used_memory(ptr: *mut LuaVmWrapper) -> usize {
+    if ptr.is_null() {
+        return 0;
+    }
+    let lua = unsafe { &(*ptr).lua };
+    lua.used_memory()
+}
+
+// Performs a full garbage collection cycle
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_collect(ptr: *mut LuaVmWrapper) -> GoNoneResult {
+    if ptr.is_null() {
+        return GoNoneResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+    let lua = unsafe { &(*ptr).lua };
+    match lua.gc_collect() {
+        Ok(_) => GoNoneResult::ok(),
+        Err(err) => GoNoneResult::err(encode_lua_error(&err)),
+    }
+}
+
+// Performs a incremental garbage collection step of the given size (in KB)
+//
+// Returns true if the step finished a collection cycle
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_step(ptr: *mut LuaVmWrapper, kbytes: i32) -> GoBoolResult {
+    if ptr.is_null() {
+        return GoBoolResult::err("LuaVmWrapper pointer is null".to_string());
+    }
+    let lua = unsafe { &(*ptr).lua };
+    match lua.gc_step_kbytes(kbytes) {
+        Ok(finished) => GoBoolResult::ok(finished),
+        Err(err) => GoBoolResult::err(encode_lua_error(&err)),
+    }
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_stop(ptr: *mut LuaVmWrapper) {
+    if ptr.is_null() {
+        return; // no-op if pointer is null
+    }
+    let lua = unsafe { &(*ptr).lua };
+    lua.gc_stop();
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_restart(ptr: *mut LuaVmWrapper) {
+    if ptr.is_null() {
+        return; // no-op if pointer is null
+    }
+    let lua = unsafe { &(*ptr).lua };
+    lua.gc_restart();
+}
+
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_is_running(ptr: *mut LuaVmWrapper) -> bool {
+    if ptr.is_null() {
+        return false;
+    }
+    let lua = unsafe { &(*ptr).lua };
+    lua.gc_is_running()
+}
+
+#[repr(C)]
+pub struct GcIncParams {
+    // The GC goal (in percent), 0 to keep the current value
+    pub goal: i32,
+    // The GC step multiplier (in percent), 0 to keep the current value
+    pub step_multiplier: i32,
+    // The GC step size (in KB), 0 to keep the current value
+    pub step_size: i32,
+}
+
+// Tunes the incremental garbage collector
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_gc_set_incremental(ptr: *mut LuaVmWrapper, params: GcIncParams) {
+    if ptr.is_null() {
+        return; // no-op if pointer is null
+    }
+    let lua = unsafe { &(*ptr).lua };
+    let mut inc = mluau::GcIncParams::default();
+    if params.goal > 0 {
+        inc = inc.goal(params.goal);
+    }
+    if params.step_multiplier > 0 {
+        inc = inc.step_multiplier(params.step_multiplier);
+    }
+    if params.step_size > 0 {
+        inc = inc.step_size(params.step_size);
+    }
+    lua.gc_set_mode(mluau::GcMode::Incremental(inc));
+}
+
+// Returns the number of interrupt ticks counted so far
+#[unsafe(no_mangle)]
+pub extern "C-unwind" fn luavm_interrupt_ticks(ptr: *mut LuaVmWrapper) -> u64 {
+    if ptr.is_null() {
+        return 0;
+    }
+    let interrupt = unsafe { &(*ptr).interrupt };
+    interrupt.ticks.load(Ordering::Relaxed)
+}
+
 #[unsafe(no_mangle)]
 pub extern "C-unwind" fn freeluavm(ptr: *mut LuaVmWrapper) {
     // Safety: Assume ptr is a valid, non-null pointer to a LuaVmWrapper
@@ -50,4 +242,4 @@ pub extern "C-unwind" fn freeluavm(ptr: *mut LuaVmWrapper) {
     unsafe {
         drop(Box::from_raw(ptr));
     }
-}
\ No newline at end of file
+}
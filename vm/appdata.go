@@ -0,0 +1,27 @@
+package vm
+
+// SetAppData attaches arbitrary application data (such as the current user,
+// tenant or a database handle) to the Lua VM, replacing any previous data.
+//
+// App data is shared between the Lua VM and the callback VMs passed
+// to a FunctionFn, so Go callbacks can access it with AppData/AppDataOf.
+func (l *GoLuaVmWrapper) SetAppData(data any) {
+	l.state.appDataMu.Lock()
+	defer l.state.appDataMu.Unlock()
+	l.state.appData = data
+}
+
+// AppData returns the application data set by SetAppData (or nil if none is set).
+func (l *GoLuaVmWrapper) AppData() any {
+	l.state.appDataMu.RLock()
+	defer l.state.appDataMu.RUnlock()
+	return l.state.appData
+}
+
+// AppDataOf returns the application data of the Lua VM as a T.
+//
+// Returns false if no app data is set or the app data is not a T.
+func AppDataOf[T any](vm *GoLuaVmWrapper) (T, bool) {
+	data, ok := vm.AppData().(T)
+	return data, ok
+}
@@ -0,0 +1,51 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+type tenant struct {
+	name string
+}
+
+func TestAppData(t *testing.T) {
+	luaVm := newVm(t)
+
+	if luaVm.AppData() != nil {
+		t.Fatalf("AppData = %v, want nil", luaVm.AppData())
+	}
+	if _, ok := vm.AppDataOf[*tenant](luaVm); ok {
+		t.Fatal("AppDataOf succeeded without app data")
+	}
+
+	luaVm.SetAppData(&tenant{name: "acme"})
+	if _, ok := vm.AppDataOf[string](luaVm); ok {
+		t.Fatal("AppDataOf succeeded with the wrong type")
+	}
+
+	// Callback VMs see the app data of their Lua VM
+	var seen string
+	fn, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, _ []vm.Value) ([]vm.Value, error) {
+		if tn, ok := vm.AppDataOf[*tenant](funcVm); ok {
+			seen = tn.name
+		}
+		// Changes made from a callback are visible to the Lua VM
+		funcVm.SetAppData(&tenant{name: "changed"})
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if _, err := fn.Call(nil); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if seen != "acme" {
+		t.Fatalf("callback saw %q, want acme", seen)
+	}
+	if tn, ok := vm.AppDataOf[*tenant](luaVm); !ok || tn.name != "changed" {
+		t.Fatalf("AppDataOf = %v, %v, want changed", tn, ok)
+	}
+}
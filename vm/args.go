@@ -0,0 +1,304 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ArgError is the error returned by the Args helpers when
+// an argument is missing or has the wrong type.
+//
+// It is formatted like the argument errors of the Luau standard
+// library, e.g. "bad argument #1 to 'greet' (string expected, got nil)".
+type ArgError struct {
+	// Position of the argument (starting at 1)
+	Pos int
+	// Name of the function, may be empty
+	Func string
+	// Description of the problem, e.g. "string expected, got nil"
+	Msg string
+}
+
+func (e *ArgError) Error() string {
+	if e.Func == "" {
+		return fmt.Sprintf("bad argument #%d (%s)", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("bad argument #%d to '%s' (%s)", e.Pos, e.Func, e.Msg)
+}
+
+// Args wraps the arguments passed to a FunctionFn, providing helpers for
+// checking their types in the same manner as the Luau standard library.
+//
+// Arguments are numbered starting at 1 (as in Luau). Missing arguments
+// are treated as nil.
+//
+//	vm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, args []vm.Value) ([]vm.Value, error) {
+//		a := vm.NewArgs("greet", args)
+//		name, err := a.CheckString(1)
+//		if err != nil {
+//			return nil, err
+//		}
+//		times, err := a.OptInteger(2, 1)
+//		...
+//	})
+type Args struct {
+	name   string
+	values []Value
+}
+
+// NewArgs wraps args. name is the name of the function the
+// arguments were passed to and is used in error messages.
+func NewArgs(name string, args []Value) *Args {
+	return &Args{name: name, values: args}
+}
+
+// Name returns the name of the function the arguments were passed to.
+func (a *Args) Name() string {
+	return a.name
+}
+
+// Len returns the number of arguments.
+func (a *Args) Len() int {
+	return len(a.values)
+}
+
+// Values returns the wrapped arguments.
+func (a *Args) Values() []Value {
+	return a.values
+}
+
+// Get returns argument n, or nil if there is no such argument.
+func (a *Args) Get(n int) Value {
+	if n < 1 || n > len(a.values) || a.values[n-1] == nil {
+		return &ValueNil{}
+	}
+	return a.values[n-1]
+}
+
+// ArgError returns an *ArgError for argument n with the given message.
+func (a *Args) ArgError(n int, msg string) error {
+	return &ArgError{Pos: n, Func: a.name, Msg: msg}
+}
+
+// typeError returns an *ArgError for argument n not being of the expected type
+func (a *Args) typeError(n int, expected string) error {
+	return a.ArgError(n, fmt.Sprintf("%s expected, got %s", expected, luauTypeName(a.Get(n))))
+}
+
+// luauTypeName returns the name of the type of v as reported by Luau's typeof
+func luauTypeName(v Value) string {
+	if v.Type() == LuaValueInteger {
+		return "number" // Luau has no separate integer type
+	}
+	return v.Type().String()
+}
+
+// isNil returns if argument n is nil (or missing)
+func (a *Args) isNil(n int) bool {
+	return a.Get(n).Type() == LuaValueNil
+}
+
+// CheckAny checks that argument n exists (it may be nil if explicitly passed).
+func (a *Args) CheckAny(n int) (Value, error) {
+	if n < 1 || n > len(a.values) {
+		return nil, a.ArgError(n, "value expected")
+	}
+	return a.Get(n), nil
+}
+
+// CheckString checks that argument n is a string and returns it.
+//
+// As in Luau, numbers are accepted and converted to strings
+// (formatted the way tostring formats them).
+func (a *Args) CheckString(n int) (string, error) {
+	switch v := a.Get(n).(type) {
+	case *ValueInteger:
+		return strconv.FormatInt(v.value, 10), nil
+	case *ValueNumber:
+		return formatNumber(v.value), nil
+	}
+	s, ok := stringValue(a.Get(n))
+	if !ok {
+		return "", a.typeError(n, "string")
+	}
+	return s, nil
+}
+
+// formatNumber formats f like Luau's tostring, using the shortest
+// representation that round-trips, in exponent form only for very
+// large or small magnitudes.
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-5 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// CheckNumber checks that argument n is a number and returns it.
+func (a *Args) CheckNumber(n int) (float64, error) {
+	switch v := a.Get(n).(type) {
+	case *ValueNumber:
+		return v.value, nil
+	case *ValueInteger:
+		return float64(v.value), nil
+	}
+	return 0, a.typeError(n, "number")
+}
+
+// CheckInteger checks that argument n is an integer (or a number
+// with an integral value) and returns it.
+func (a *Args) CheckInteger(n int) (int64, error) {
+	switch v := a.Get(n).(type) {
+	case *ValueInteger:
+		return v.value, nil
+	case *ValueNumber:
+		if v.value != math.Trunc(v.value) || v.value < math.MinInt64 || v.value >= math.MaxInt64 {
+			return 0, a.ArgError(n, "number has no integer representation")
+		}
+		return int64(v.value), nil
+	}
+	return 0, a.typeError(n, "number")
+}
+
+// CheckBoolean checks that argument n is a boolean and returns it.
+func (a *Args) CheckBoolean(n int) (bool, error) {
+	v, ok := a.Get(n).(*ValueBoolean)
+	if !ok {
+		return false, a.typeError(n, "boolean")
+	}
+	return v.value, nil
+}
+
+// CheckVector checks that argument n is a vector and returns it.
+func (a *Args) CheckVector(n int) ([3]float32, error) {
+	v, ok := a.Get(n).(*ValueVector)
+	if !ok {
+		return [3]float32{}, a.typeError(n, "vector")
+	}
+	return v.value, nil
+}
+
+// CheckTable checks that argument n is a table and returns it.
+func (a *Args) CheckTable(n int) (*LuaTable, error) {
+	v, ok := a.Get(n).(*ValueTable)
+	if !ok {
+		return nil, a.typeError(n, "table")
+	}
+	return v.value, nil
+}
+
+// CheckFunction checks that argument n is a function and returns it.
+func (a *Args) CheckFunction(n int) (*LuaFunction, error) {
+	v, ok := a.Get(n).(*ValueFunction)
+	if !ok {
+		return nil, a.typeError(n, "function")
+	}
+	return v.value, nil
+}
+
+// CheckThread checks that argument n is a thread and returns it.
+func (a *Args) CheckThread(n int) (*LuaThread, error) {
+	v, ok := a.Get(n).(*ValueThread)
+	if !ok {
+		return nil, a.typeError(n, "thread")
+	}
+	return v.value, nil
+}
+
+// CheckBuffer checks that argument n is a buffer and returns it.
+func (a *Args) CheckBuffer(n int) (*LuaBuffer, error) {
+	v, ok := a.Get(n).(*ValueBuffer)
+	if !ok {
+		return nil, a.typeError(n, "buffer")
+	}
+	return v.value, nil
+}
+
+// CheckUserData checks that argument n is a userdata and returns it.
+func (a *Args) CheckUserData(n int) (*LuaUserData, error) {
+	v, ok := a.Get(n).(*ValueUserData)
+	if !ok {
+		return nil, a.typeError(n, "userdata")
+	}
+	return v.value, nil
+}
+
+// CheckUserData checks that argument n is a userdata whose associated
+// data is a T and returns the associated data.
+func CheckUserData[T any](a *Args, n int) (T, error) {
+	var zero T
+	expected := reflect.TypeOf((*T)(nil)).Elem().String()
+
+	ud, ok := a.Get(n).(*ValueUserData)
+	if !ok {
+		return zero, a.typeError(n, expected)
+	}
+	data, err := ud.value.AssociatedData()
+	if err != nil {
+		return zero, a.typeError(n, expected)
+	}
+	typed, ok := data.(T)
+	if !ok {
+		return zero, a.typeError(n, expected)
+	}
+	return typed, nil
+}
+
+// OptString returns argument n if it is a string (or a number,
+// see CheckString), or def if it is nil.
+func (a *Args) OptString(n int, def string) (string, error) {
+	if a.isNil(n) {
+		return def, nil
+	}
+	return a.CheckString(n)
+}
+
+// OptNumber returns argument n if it is a number, or def if it is nil.
+func (a *Args) OptNumber(n int, def float64) (float64, error) {
+	if a.isNil(n) {
+		return def, nil
+	}
+	return a.CheckNumber(n)
+}
+
+// OptInteger returns argument n if it is an integer, or def if it is nil.
+func (a *Args) OptInteger(n int, def int64) (int64, error) {
+	if a.isNil(n) {
+		return def, nil
+	}
+	return a.CheckInteger(n)
+}
+
+// OptBoolean returns argument n if it is a boolean, or def if it is nil.
+func (a *Args) OptBoolean(n int, def bool) (bool, error) {
+	if a.isNil(n) {
+		return def, nil
+	}
+	return a.CheckBoolean(n)
+}
+
+// OptTable returns argument n if it is a table, or nil if it is nil.
+func (a *Args) OptTable(n int) (*LuaTable, error) {
+	if a.isNil(n) {
+		return nil, nil
+	}
+	return a.CheckTable(n)
+}
+
+// OptFunction returns argument n if it is a function, or nil if it is nil.
+func (a *Args) OptFunction(n int) (*LuaFunction, error) {
+	if a.isNil(n) {
+		return nil, nil
+	}
+	return a.CheckFunction(n)
+}
@@ -0,0 +1,138 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestArgsCheck(t *testing.T) {
+	a := vm.NewArgs("greet", []vm.Value{
+		vm.GoString("bob"),
+		vm.NewValueNumber(3),
+		vm.NewValueBoolean(true),
+		&vm.ValueNil{},
+	})
+
+	if s, err := a.CheckString(1); err != nil || s != "bob" {
+		t.Errorf("CheckString(1) = %q, %v", s, err)
+	}
+	if i, err := a.CheckInteger(2); err != nil || i != 3 {
+		t.Errorf("CheckInteger(2) = %d, %v", i, err)
+	}
+	if b, err := a.CheckBoolean(3); err != nil || !b {
+		t.Errorf("CheckBoolean(3) = %v, %v", b, err)
+	}
+	if n, err := a.OptNumber(4, 1.5); err != nil || n != 1.5 {
+		t.Errorf("OptNumber(4) = %v, %v, want the default", n, err)
+	}
+	if n, err := a.OptNumber(9, 2.5); err != nil || n != 2.5 {
+		t.Errorf("OptNumber(9) = %v, %v, want the default", n, err)
+	}
+	if _, err := a.CheckAny(4); err != nil {
+		t.Errorf("CheckAny(4) of an explicit nil: %v", err)
+	}
+}
+
+func TestArgsCheckStringNumber(t *testing.T) {
+	a := vm.NewArgs("greet", []vm.Value{
+		vm.NewValueInteger(-12),
+		vm.NewValueNumber(3),
+		vm.NewValueNumber(0.1),
+		vm.NewValueNumber(1e21),
+		vm.NewValueBoolean(true),
+	})
+
+	for n, want := range []string{"-12", "3", "0.1", "1e+21"} {
+		if s, err := a.CheckString(n + 1); err != nil || s != want {
+			t.Errorf("CheckString(%d) = %q, %v, want %q", n+1, s, err, want)
+		}
+	}
+	if s, err := a.OptString(2, "x"); err != nil || s != "3" {
+		t.Errorf("OptString(2) = %q, %v, want %q", s, err, "3")
+	}
+	if _, err := a.CheckString(5); err == nil || err.Error() != "bad argument #5 to 'greet' (string expected, got boolean)" {
+		t.Errorf("CheckString(5) of a boolean: err = %v", err)
+	}
+}
+
+func TestArgsErrors(t *testing.T) {
+	a := vm.NewArgs("greet", []vm.Value{vm.NewValueNumber(1.5), vm.GoString("x")})
+
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{second(a.CheckString(3)), "bad argument #3 to 'greet' (string expected, got nil)"},
+		{second(a.CheckTable(2)), "bad argument #2 to 'greet' (table expected, got string)"},
+		{second(a.CheckInteger(1)), "bad argument #1 to 'greet' (number has no integer representation)"},
+		{second(a.OptNumber(2, 0)), "bad argument #2 to 'greet' (number expected, got string)"},
+		{second(a.CheckAny(3)), "bad argument #3 to 'greet' (value expected)"},
+		{second(vm.NewArgs("", nil).CheckBoolean(1)), "bad argument #1 (boolean expected, got nil)"},
+	} {
+		var argErr *vm.ArgError
+		if !errors.As(tc.err, &argErr) {
+			t.Errorf("err = %#v, want *ArgError", tc.err)
+			continue
+		}
+		if tc.err.Error() != tc.want {
+			t.Errorf("err = %q, want %q", tc.err, tc.want)
+		}
+	}
+}
+
+// second returns the error of a Check/Opt call
+func second[T any](_ T, err error) error {
+	return err
+}
+
+type counter struct {
+	n int
+}
+
+func TestArgsUserData(t *testing.T) {
+	luaVm := newVm(t)
+
+	ud, err := luaVm.CreateUserData(&counter{n: 4}, nil)
+	if err != nil {
+		t.Fatalf("CreateUserData: %v", err)
+	}
+	defer ud.Close()
+	a := vm.NewArgs("inc", []vm.Value{ud.ToValue(), vm.GoString("x")})
+
+	c, err := vm.CheckUserData[*counter](a, 1)
+	if err != nil || c.n != 4 {
+		t.Fatalf("CheckUserData = %v, %v", c, err)
+	}
+	if _, err := vm.CheckUserData[string](a, 1); err == nil {
+		t.Fatal("CheckUserData with the wrong type succeeded")
+	}
+	_, err = vm.CheckUserData[*counter](a, 2)
+	if err == nil || err.Error() != "bad argument #2 to 'inc' (*vm_test.counter expected, got string)" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestArgsFromLuau(t *testing.T) {
+	luaVm := newVm(t)
+
+	fn, err := luaVm.CreateFunction(func(_ *vm.GoLuaVmWrapper, args []vm.Value) ([]vm.Value, error) {
+		_, err := vm.NewArgs("greet", args).CheckString(1)
+		return nil, err
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("greet", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+
+	rets := exec(t, luaVm, `local ok, err = pcall(greet, {}) return tostring(err)`)
+	want := "bad argument #1 to 'greet' (string expected, got table)"
+	if got := decode[string](t, luaVm, rets[0]); !strings.Contains(got, want) {
+		t.Fatalf("error = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetExceeded is matched (via errors.Is) by every BudgetExceededError
+var ErrBudgetExceeded = errors.New("instruction budget exceeded")
+
+// BudgetExceededError is returned when Luau code is stopped for
+// using more than the instruction budget of the Lua VM.
+type BudgetExceededError struct {
+	// The budget (in interrupt ticks) of the call
+	Budget uint64
+	// The number of interrupt ticks used by the call before it was stopped
+	Used uint64
+	// The underlying error returned by Luau
+	Err error
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("instruction budget exceeded (used %d of %d ticks)", e.Used, e.Budget)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// SetInstructionBudget sets the maximum number of interrupt ticks a single
+// LuaFunction.Call (or ExecChunk etc.) may use before it is stopped with a
+// BudgetExceededError. Nested calls (calls made from Go callbacks called by
+// Luau) count towards the budget of the outermost call, while calls made
+// concurrently from other goroutines each have a budget of their own.
+//
+// An interrupt tick is counted by Luau at every loop iteration and function
+// call, which makes it a cheap approximation of the number of instructions
+// executed.
+//
+// A budget of 0 (the default) disables the budget.
+func (l *GoLuaVmWrapper) SetInstructionBudget(ticks uint64) {
+	l.state.budget.Store(ticks)
+}
+
+// InstructionBudget returns the instruction budget set by SetInstructionBudget
+func (l *GoLuaVmWrapper) InstructionBudget() uint64 {
+	return l.state.budget.Load()
+}
+
+// InterruptTicks returns the total number of interrupt ticks counted
+// by the Lua VM since it was created.
+func (l *GoLuaVmWrapper) InterruptTicks() uint64 {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return 0 // A closed VM has no ticks
+	}
+	return uint64(C.luavm_interrupt_ticks(lua))
+}
+
+// meter calls fn (which should call into Luau as part of call) while
+// enforcing the instruction budget of the Lua VM and stopping the Luau code
+// once ctx is cancelled, returning the number of interrupt ticks used by fn.
+//
+// The budget and interrupt are kept per call by the Rust side, so calls made
+// concurrently from other goroutines do not affect each other.
+func (l *GoLuaVmWrapper) meter(ctx context.Context, fn func(call *C.struct_CallState) error) (uint64, error) {
+	budget := l.state.budget.Load()
+	call := C.luago_new_call(C.uint64_t(budget))
+	defer C.luago_free_call(call)
+
+	stop := watchContext(ctx, call)
+	err := fn(call)
+	used := uint64(C.luago_call_ticks(call))
+	if interrupted := stop(); interrupted && err != nil {
+		return used, fmt.Errorf("luau execution interrupted: %w", ctx.Err())
+	}
+	if err != nil && budget != 0 && used >= budget {
+		err = &BudgetExceededError{Budget: budget, Used: used, Err: err}
+	}
+	return used, err
+}
@@ -0,0 +1,90 @@
+package vm_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestInstructionBudget(t *testing.T) {
+	luaVm := newVm(t)
+	luaVm.SetInstructionBudget(10_000)
+
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `while true do end`})
+	var budgetErr *vm.BudgetExceededError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, vm.ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want BudgetExceededError", err)
+	}
+
+	// The budget is per call
+	exec(t, luaVm, `local n = 0 for i = 1, 10 do n += i end`)
+}
+
+func TestInstructionBudgetNested(t *testing.T) {
+	luaVm := newVm(t)
+	luaVm.SetInstructionBudget(10_000)
+
+	// Nested calls count towards the budget of the outermost call
+	// instead of starting a budget of their own
+	fn, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, _ []vm.Value) ([]vm.Value, error) {
+		_, err := funcVm.ExecChunk(vm.ChunkOpts{Name: "nested", Code: `for i = 1, 100 do end`})
+		return nil, err
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("nested", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `while true do nested() end`})
+	if !errors.Is(err, vm.ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestInstructionBudgetConcurrent(t *testing.T) {
+	luaVm := newVm(t)
+	luaVm.SetInstructionBudget(10_000)
+
+	started := make(chan struct{})
+	var once sync.Once
+	fn, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		once.Do(func() {
+			close(started)
+			// Give the other goroutine time to start its call
+			time.Sleep(20 * time.Millisecond)
+		})
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("started", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+
+	// Each call keeps its own budget, whichever returns first
+	first := make(chan error, 1)
+	go func() {
+		_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "first", Code: `while true do started() end`})
+		first <- err
+	}()
+	<-started
+	second := make(chan error, 1)
+	go func() {
+		_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "second", Code: `while true do end`})
+		second <- err
+	}()
+
+	if err := waitErr(t, first); !errors.Is(err, vm.ErrBudgetExceeded) {
+		t.Fatalf("first = %v, want ErrBudgetExceeded", err)
+	}
+	if err := waitErr(t, second); !errors.Is(err, vm.ErrBudgetExceeded) {
+		t.Fatalf("second = %v, want ErrBudgetExceeded", err)
+	}
+}
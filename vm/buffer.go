@@ -0,0 +1,229 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"unsafe"
+)
+
+var bufferTab = objectTab{
+	name: "LuaBuffer",
+	dtor: func(ptr *C.void) {
+		C.luago_free_buffer((*C.struct_LuaBuffer)(unsafe.Pointer(ptr)))
+	},
+}
+
+// A LuaBuffer is an abstraction over a Luau buffer object.
+//
+// Luau buffers are fixed-size, mutable blocks of bytes. The contents of a
+// buffer are never moved by the Luau GC, so LuaBuffer can give Go direct
+// (zero-copy) access to them through WithBytes.
+//
+// LuaBuffer implements io.ReaderAt and io.WriterAt.
+type LuaBuffer struct {
+	lua    *GoLuaVmWrapper // The Lua VM wrapper that owns this buffer
+	object *object
+}
+
+var (
+	_ io.ReaderAt = (*LuaBuffer)(nil)
+	_ io.WriterAt = (*LuaBuffer)(nil)
+)
+
+// CreateBuffer creates a new zero-filled Luau buffer of the given size.
+func (l *GoLuaVmWrapper) CreateBuffer(size int) (*LuaBuffer, error) {
+	if size < 0 {
+		return nil, errors.New("buffer size cannot be negative")
+	}
+
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	res := C.luago_create_buffer(lua, C.size_t(size))
+	if res.error != nil {
+		return nil, moveErrorToGoError(res.error)
+	}
+	return &LuaBuffer{object: newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab, l.state), lua: l.owner()}, nil
+}
+
+// CreateBufferFrom creates a new Luau buffer containing a copy of data.
+func (l *GoLuaVmWrapper) CreateBufferFrom(data []byte) (*LuaBuffer, error) {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	var dataPtr *C.char
+	if len(data) > 0 {
+		dataPtr = (*C.char)(unsafe.Pointer(&data[0]))
+	}
+
+	res := C.luago_create_buffer_from(lua, dataPtr, C.size_t(len(data)))
+	if res.error != nil {
+		return nil, moveErrorToGoError(res.error)
+	}
+	return &LuaBuffer{object: newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab, l.state), lua: l.owner()}, nil
+}
+
+// bytesNoLock returns the contents of the buffer as a slice backed
+// by Luau-owned memory. The caller must hold the object lock for as
+// long as the slice is in use.
+func (l *LuaBuffer) bytesNoLock() ([]byte, error) {
+	ptr, err := l.object.PointerNoLock()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+
+	l.lua.obj.RLock()
+	defer l.lua.obj.RUnlock()
+	lua, err := l.lua.lua()
+	if err != nil {
+		return nil, err // Return error if the owning VM is closed
+	}
+
+	data := C.luago_buffer_as_bytes(lua, (*C.struct_LuaBuffer)(unsafe.Pointer(ptr)))
+	if data.data == nil || data.len == 0 {
+		return []byte{}, nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(data.data)), int(data.len)), nil
+}
+
+// Len returns the size of the buffer in bytes.
+//
+// Returns 0 if the buffer is closed.
+func (l *LuaBuffer) Len() int {
+	l.object.RLock()
+	defer l.object.RUnlock()
+	ptr, err := l.object.PointerNoLock()
+	if err != nil {
+		return 0 // Return 0 if the object is closed
+	}
+	return int(C.luago_buffer_len((*C.struct_LuaBuffer)(unsafe.Pointer(ptr))))
+}
+
+// ReadAt copies len(p) bytes starting at offset off of the buffer into p.
+//
+// As per io.ReaderAt, if fewer than len(p) bytes are available, ReadAt
+// returns the number of bytes read along with io.EOF.
+func (l *LuaBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	data, err := l.bytesNoLock()
+	if err != nil {
+		return 0, err
+	}
+
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt copies p into the buffer starting at offset off.
+//
+// Luau buffers cannot grow, so if p does not fit in the buffer, only the
+// bytes that fit are written and io.ErrShortWrite is returned.
+func (l *LuaBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	data, err := l.bytesNoLock()
+	if err != nil {
+		return 0, err
+	}
+
+	if off > int64(len(data)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(data[off:], p)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Bytes returns a copy of the contents of the buffer.
+//
+// Returns nil if the buffer is closed.
+func (l *LuaBuffer) Bytes() []byte {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	data, err := l.bytesNoLock()
+	if err != nil {
+		return nil // Return nil if the object is closed
+	}
+	return append([]byte(nil), data...)
+}
+
+// WithBytes calls fn with a slice that directly aliases the contents of
+// the buffer, allowing it to be read and modified without copying.
+//
+// The slice must not be used (or retained) after fn returns, and must not
+// be accessed while Luau code that uses the buffer is running. The buffer
+// cannot be closed while fn is running.
+func (l *LuaBuffer) WithBytes(fn func(b []byte)) error {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	data, err := l.bytesNoLock()
+	if err != nil {
+		return err // Return error if the object is closed
+	}
+	fn(data)
+	return nil
+}
+
+// Returns a 'pointer' to a Lua-owned buffer
+//
+// This pointer is only useful for hashing/debugging
+// and cannot be converted back to the original Lua buffer object.
+func (l *LuaBuffer) Pointer() uint64 {
+	l.object.RLock()
+	defer l.object.RUnlock()
+	lptr, err := l.object.PointerNoLock()
+	if err != nil {
+		return 0 // Return 0 if the object is closed
+	}
+
+	ptr := C.luago_buffer_to_pointer((*C.struct_LuaBuffer)(unsafe.Pointer(lptr)))
+	return uint64(ptr)
+}
+
+// ToValue converts the LuaBuffer to a Value.
+func (l *LuaBuffer) ToValue() Value {
+	return &ValueBuffer{value: l}
+}
+
+func (l *LuaBuffer) Close() {
+	if l == nil || l.object == nil {
+		return // Nothing to close
+	}
+	// Close the LuaBuffer object
+	l.object.Close()
+}
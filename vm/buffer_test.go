@@ -0,0 +1,113 @@
+package vm_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestBufferRoundTrip(t *testing.T) {
+	luaVm := newVm(t)
+
+	buf, err := luaVm.CreateBuffer(4)
+	if err != nil {
+		t.Fatalf("CreateBuffer: %v", err)
+	}
+	defer buf.Close()
+
+	if n, err := buf.WriteAt([]byte{1, 2, 3, 4}, 0); err != nil || n != 4 {
+		t.Fatalf("WriteAt = %d, %v", n, err)
+	}
+	if err := luaVm.SetGlobal("buf", buf.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+
+	// Bytes written from Go are visible to Luau
+	rets := exec(t, luaVm, `return buffer.len(buf), buffer.readu8(buf, 0), buffer.readu8(buf, 3)`)
+	if got := [3]int{decode[int](t, luaVm, rets[0]), decode[int](t, luaVm, rets[1]), decode[int](t, luaVm, rets[2])}; got != [3]int{4, 1, 4} {
+		t.Fatalf("buffer.len/readu8 = %v, want [4 1 4]", got)
+	}
+
+	// Bytes written from Luau are visible to Go
+	exec(t, luaVm, `buffer.writeu8(buf, 1, 200)`)
+	if got := buf.Bytes(); !bytes.Equal(got, []byte{1, 200, 3, 4}) {
+		t.Fatalf("Bytes = %v, want [1 200 3 4]", got)
+	}
+
+	// WithBytes aliases the buffer contents
+	if err := buf.WithBytes(func(b []byte) { b[2] = 42 }); err != nil {
+		t.Fatalf("WithBytes: %v", err)
+	}
+	rets = exec(t, luaVm, `return buffer.readu8(buf, 2)`)
+	if got := decode[int](t, luaVm, rets[0]); got != 42 {
+		t.Fatalf("buffer.readu8(buf, 2) = %d, want 42", got)
+	}
+
+	p := make([]byte, 4)
+	if n, err := buf.ReadAt(p, 2); n != 2 || !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadAt past end = %d, %v, want 2, EOF", n, err)
+	}
+	if n, err := buf.WriteAt([]byte{9, 9, 9}, 2); n != 2 || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("WriteAt past end = %d, %v, want 2, ErrShortWrite", n, err)
+	}
+}
+
+func TestBufferFrom(t *testing.T) {
+	luaVm := newVm(t)
+
+	buf, err := luaVm.CreateBufferFrom([]byte("hello"))
+	if err != nil {
+		t.Fatalf("CreateBufferFrom: %v", err)
+	}
+	defer buf.Close()
+
+	if err := luaVm.SetGlobal("buf", buf.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	rets := exec(t, luaVm, `return buffer.tostring(buf)`)
+	if got := decode[string](t, luaVm, rets[0]); got != "hello" {
+		t.Fatalf("buffer.tostring = %q, want hello", got)
+	}
+
+	empty, err := luaVm.CreateBufferFrom(nil)
+	if err != nil {
+		t.Fatalf("CreateBufferFrom(nil): %v", err)
+	}
+	defer empty.Close()
+	if empty.Len() != 0 || len(empty.Bytes()) != 0 {
+		t.Fatalf("empty buffer has length %d", empty.Len())
+	}
+}
+
+func TestBufferClosed(t *testing.T) {
+	luaVm := newVm(t)
+
+	buf, err := luaVm.CreateBuffer(1)
+	if err != nil {
+		t.Fatalf("CreateBuffer: %v", err)
+	}
+	buf.Close()
+	if err := buf.WithBytes(func([]byte) {}); err == nil {
+		t.Fatal("WithBytes on closed buffer succeeded")
+	}
+}
+
+func TestBufferValueClosed(t *testing.T) {
+	luaVm := newVm(t)
+
+	// Zero and closed buffers are rejected instead of panicking
+	if err := luaVm.SetGlobal("buf", &vm.ValueBuffer{}); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("SetGlobal with a zero ValueBuffer = %v, want ErrClosed", err)
+	}
+	buf, err := luaVm.CreateBuffer(4)
+	if err != nil {
+		t.Fatalf("CreateBuffer: %v", err)
+	}
+	buf.Close()
+	if err := luaVm.SetGlobal("buf", buf.ToValue()); err == nil {
+		t.Fatal("SetGlobal with a closed buffer succeeded")
+	}
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// CallInto calls fn with args (converted using the same rules as ToValue)
+// and decodes the values returned by fn into outs (which must be pointers),
+// using the same rules as FromValue.
+//
+//	var name string
+//	var age int
+//	err := vm.CallInto(fn, []any{userId}, &name, &age)
+//
+// An error is returned if fn returns fewer values than there are outs.
+// Extra return values are ignored. If fn (or the Lua VM owning it) is
+// closed, an error matching ErrClosed is returned without converting args.
+func CallInto(fn *LuaFunction, args []any, outs ...any) error {
+	if fn == nil {
+		return errors.New("function cannot be nil")
+	}
+	if _, err := fn.object.PointerLock(); err != nil {
+		return err
+	}
+	if _, err := fn.lua.obj.PointerLock(); err != nil {
+		return err
+	}
+
+	for i, out := range outs {
+		rv := reflect.ValueOf(out)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return fmt.Errorf("out #%d must be a non-nil pointer, got %T", i+1, out)
+		}
+	}
+
+	values := make([]Value, 0, len(args))
+	var owned []Value
+	defer func() {
+		for _, v := range owned {
+			v.Close()
+		}
+	}()
+	for i, arg := range args {
+		e := &encoder{lua: fn.lua, visiting: map[visitKey]struct{}{}}
+		v, isOwned, err := e.encode(reflect.ValueOf(arg), "")
+		if err != nil {
+			return fmt.Errorf("cannot convert argument #%d: %w", i+1, err)
+		}
+		if isOwned {
+			owned = append(owned, v)
+		}
+		values = append(values, v)
+	}
+
+	rets, err := fn.Call(values)
+	if err != nil {
+		return err
+	}
+
+	retained := make([]bool, len(rets))
+	defer func() {
+		for i, ret := range rets {
+			if !retained[i] {
+				ret.Close()
+			}
+		}
+	}()
+
+	if len(rets) < len(outs) {
+		return fmt.Errorf("function returned %d values, expected at least %d", len(rets), len(outs))
+	}
+
+	d := &decoder{lua: fn.lua}
+	for i, out := range outs {
+		isRetained, err := d.decode(rets[i], reflect.ValueOf(out).Elem(), "")
+		if err != nil {
+			return fmt.Errorf("cannot decode return value #%d: %w", i+1, err)
+		}
+		retained[i] = isRetained
+	}
+	return nil
+}
+
+// Call1 calls fn with args like CallInto, decoding the first
+// value returned by fn into a T.
+//
+//	greeting, err := vm.Call1[string](fn, "world")
+func Call1[T any](fn *LuaFunction, args ...any) (T, error) {
+	var out T
+	if err := CallInto(fn, args, &out); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
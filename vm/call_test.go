@@ -0,0 +1,150 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// loadFunc returns the function returned by code
+func loadFunc(t *testing.T, luaVm *vm.GoLuaVmWrapper, code string) *vm.LuaFunction {
+	t.Helper()
+	rets := exec(t, luaVm, code)
+	fn, ok := rets[0].(*vm.ValueFunction)
+	if !ok {
+		t.Fatalf("code returned %#v, want function", rets[0])
+	}
+	return fn.Value()
+}
+
+func TestCallInto(t *testing.T) {
+	luaVm := newVm(t)
+	fn := loadFunc(t, luaVm, `return function(user) return user.name, #user.tags end`)
+	defer fn.Close()
+
+	user := struct {
+		Name string   `lua:"name"`
+		Tags []string `lua:"tags"`
+	}{"ada", []string{"a", "b"}}
+	var name string
+	var ntags int
+	if err := vm.CallInto(fn, []any{user}, &name, &ntags); err != nil {
+		t.Fatalf("CallInto: %v", err)
+	}
+	if name != "ada" || ntags != 2 {
+		t.Fatalf("CallInto = %q, %d, want ada, 2", name, ntags)
+	}
+
+	var extra string
+	if err := vm.CallInto(fn, []any{user}, &name, &ntags, &extra); err == nil {
+		t.Fatal("CallInto with more outs than return values succeeded")
+	}
+	if err := vm.CallInto(fn, []any{user}, name); err == nil {
+		t.Fatal("CallInto with non-pointer out succeeded")
+	}
+}
+
+func TestCall1(t *testing.T) {
+	luaVm := newVm(t)
+	fn := loadFunc(t, luaVm, `return function(a, b) return a .. ", " .. b end`)
+	defer fn.Close()
+
+	got, err := vm.Call1[string](fn, "hello", "world")
+	if err != nil {
+		t.Fatalf("Call1: %v", err)
+	}
+	if got != "hello, world" {
+		t.Fatalf("Call1 = %q, want hello, world", got)
+	}
+}
+
+func TestCallClosed(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+	fn := loadFunc(t, luaVm, `return function() return 1 end`)
+	closedFn := loadFunc(t, luaVm, `return function() return 1 end`)
+	closedFn.Close()
+
+	if _, err := vm.Call1[int](closedFn); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("Call1 on closed function = %v, want ErrClosed", err)
+	}
+
+	luaVm.Close()
+	if _, err := vm.Call1[int](fn); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("Call1 on closed VM = %v, want ErrClosed", err)
+	}
+	if err := vm.CallInto(fn, []any{map[string]int{"a": 1}}); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("CallInto on closed VM = %v, want ErrClosed", err)
+	}
+}
+
+func TestCallWithHandler(t *testing.T) {
+	luaVm := newVm(t)
+	fn := loadFunc(t, luaVm, `return function(fail)
+		if fail then error("boom", 0) end
+		return "ok"
+	end`)
+	defer fn.Close()
+	handler := loadFunc(t, luaVm, `return function(err) return { wrapped = err } end`)
+	defer handler.Close()
+
+	rets, err := fn.CallWithHandler([]vm.Value{vm.NewValueBoolean(false)}, handler)
+	if err != nil {
+		t.Fatalf("CallWithHandler: %v", err)
+	}
+	if got := decode[string](t, luaVm, rets[0]); got != "ok" {
+		t.Fatalf("returned %q, want ok", got)
+	}
+
+	// The value returned by the handler replaces the error value
+	_, err = fn.CallWithHandler([]vm.Value{vm.NewValueBoolean(true)}, handler)
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	var payload struct {
+		Wrapped string `lua:"wrapped"`
+	}
+	if err := luaVm.FromValue(luaErr.Value(), &payload); err != nil {
+		t.Fatalf("FromValue: %v", err)
+	}
+	if payload.Wrapped != "boom" {
+		t.Fatalf("wrapped = %q, want boom", payload.Wrapped)
+	}
+
+	if _, err := fn.CallWithHandler(nil, nil); err == nil {
+		t.Fatal("CallWithHandler with a nil handler succeeded")
+	}
+}
+
+func TestCallWithGoHandler(t *testing.T) {
+	luaVm := newVm(t)
+	fn := loadFunc(t, luaVm, `return function() error("boom", 0) end`)
+	defer fn.Close()
+
+	var seen string
+	handler, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, args []vm.Value) ([]vm.Value, error) {
+		seen = decode[string](t, funcVm, args[0])
+		return []vm.Value{vm.GoString("handled: " + seen)}, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer handler.Close()
+
+	_, err = fn.CallWithHandler(nil, handler)
+	if seen != "boom" {
+		t.Fatalf("handler saw %q, want boom", seen)
+	}
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	if !strings.Contains(luaErr.Message, "handled: boom") {
+		t.Fatalf("Message = %q, want the handler's value", luaErr.Message)
+	}
+}
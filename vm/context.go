@@ -0,0 +1,66 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"context"
+)
+
+// watchContext requests that call be interrupted once ctx is cancelled
+// or its deadline is exceeded.
+//
+// The returned function must be called once the call has returned. It stops
+// watching ctx and returns whether the call was interrupted.
+func watchContext(ctx context.Context, call *C.struct_CallState) func() bool {
+	if ctx.Done() == nil {
+		// Context can never be cancelled, nothing to watch
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	exited := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			C.luago_call_interrupt(call)
+			exited <- true
+		case <-done:
+			exited <- false
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		return <-exited
+	}
+}
+
+// Context returns the context passed to the innermost CallContext (or
+// ExecChunkContext) call running on the Lua VM, allowing Go callbacks
+// to observe cancellation of the Luau code calling them.
+//
+// Returns context.Background() if no such call is running.
+func (l *GoLuaVmWrapper) Context() context.Context {
+	state := l.vmState()
+	if state == nil {
+		return context.Background()
+	}
+	if ctx := state.callCtx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
+
+// enterContext makes ctx the context returned by Context until the
+// returned function is called (which restores the previous context)
+func (s *vmState) enterContext(ctx context.Context) func() {
+	if s == nil {
+		return func() {}
+	}
+	prev := s.callCtx.Swap(&ctx)
+	return func() {
+		s.callCtx.Store(prev)
+	}
+}
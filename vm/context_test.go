@@ -0,0 +1,118 @@
+package vm_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestCallContextCancel(t *testing.T) {
+	luaVm := newVm(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := luaVm.ExecChunkContext(ctx, vm.ChunkOpts{Name: "test", Code: `while true do end`})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+
+	// The interrupt does not affect later calls
+	exec(t, luaVm, `return 1`)
+}
+
+func TestCallContextConcurrent(t *testing.T) {
+	luaVm := newVm(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan struct{})
+	var once sync.Once
+	fn, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		once.Do(func() { close(started) })
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("started", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := luaVm.ExecChunkContext(ctx, vm.ChunkOpts{Name: "cancelled", Code: `while true do started() end`})
+		cancelled <- err
+	}()
+	<-started
+
+	// A call made by another goroutine meanwhile is not interrupted
+	// by the cancellation of the first call
+	other := make(chan error, 1)
+	go func() {
+		_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "other", Code: `for i = 1, 1000 do end`})
+		other <- err
+	}()
+	cancel()
+
+	if err := waitErr(t, cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled call = %v, want Canceled", err)
+	}
+	if err := waitErr(t, other); err != nil {
+		t.Fatalf("other call = %v, want nil", err)
+	}
+}
+
+func TestCallbackVmObjects(t *testing.T) {
+	luaVm := newVm(t)
+
+	// Objects created through a callback VM belong to the Lua VM
+	// and can be used once the callback returns
+	var kept *vm.LuaFunction
+	var keptThread *vm.LuaThread
+	fn, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, _ []vm.Value) ([]vm.Value, error) {
+		var err error
+		kept, err = funcVm.LoadChunk(vm.ChunkOpts{Name: "kept", Code: `return 1`})
+		if err != nil {
+			return nil, err
+		}
+		keptThread, err = funcVm.CreateThread(kept)
+		return nil, err
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("keep", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	exec(t, luaVm, `keep()`)
+	defer kept.Close()
+	defer keptThread.Close()
+
+	rets, err := kept.Call(nil)
+	if err != nil {
+		t.Fatalf("calling function created through a callback VM: %v", err)
+	}
+	if got := decode[int](t, luaVm, rets[0]); got != 1 {
+		t.Fatalf("returned %d, want 1", got)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := kept.CallContext(ctx, nil); err != nil {
+		t.Fatalf("CallContext on function created through a callback VM: %v", err)
+	}
+	if _, err := keptThread.Resume(nil); err != nil {
+		t.Fatalf("resuming thread created through a callback VM: %v", err)
+	}
+
+	// Once the Lua VM itself is closed, they can no longer be used
+	luaVm.Close()
+	if _, err := kept.Call(nil); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("calling function of closed VM = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,173 @@
+package vm
+
+import (
+	"reflect"
+)
+
+// LuaMarshaler is implemented by types that provide their own Luau
+// representation when converted by ToValue.
+//
+// The returned Value is owned by the conversion and may be closed
+// once it has been stored in an enclosing table, unless the type
+// implements LuaValueRetainer.
+type LuaMarshaler interface {
+	MarshalLua(vm *GoLuaVmWrapper) (Value, error)
+}
+
+// LuaUnmarshaler is implemented by types that decode themselves from a
+// Luau value when used as a FromValue target.
+//
+// v is closed once decoding finishes, unless the type implements
+// LuaValueRetainer. UnmarshalLua is also called for nil values.
+type LuaUnmarshaler interface {
+	UnmarshalLua(vm *GoLuaVmWrapper, v Value) error
+}
+
+// LuaValueRetainer can be implemented by a LuaMarshaler or LuaUnmarshaler
+// that keeps the Value returned by MarshalLua (or passed to UnmarshalLua).
+//
+// RetainsLuaValue is called after each successful conversion. If it
+// returns true, the conversion leaves the Value open and closing it
+// is up to the type.
+type LuaValueRetainer interface {
+	RetainsLuaValue() bool
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*LuaMarshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*LuaUnmarshaler)(nil)).Elem()
+)
+
+// retainsValue returns true if x retains the Value of its conversion (see LuaValueRetainer)
+func retainsValue(x any) bool {
+	r, ok := x.(LuaValueRetainer)
+	return ok && r.RetainsLuaValue()
+}
+
+// converter is a type-erased converter registered with RegisterConverter
+type converter struct {
+	toValue   func(vm *GoLuaVmWrapper, v reflect.Value) (Value, error)
+	fromValue func(vm *GoLuaVmWrapper, v Value, target reflect.Value) error
+}
+
+// RegisterConverter registers functions converting values of type T
+// to and from Luau values, for types that cannot implement LuaMarshaler
+// and LuaUnmarshaler themselves (such as time.Time).
+//
+// Converters take precedence over LuaMarshaler/LuaUnmarshaler and the
+// built-in conversion rules of ToValue/FromValue. Either function may be
+// nil, in which case the default conversion is used in that direction.
+// The Value returned by toValue is owned by the conversion (see LuaMarshaler)
+// and the Value passed to fromValue is closed once decoding finishes.
+// Registering a converter for a type replaces any previous converter.
+//
+// Converters are shared between the Lua VM and its callback VMs.
+func RegisterConverter[T any](vm *GoLuaVmWrapper, toValue func(vm *GoLuaVmWrapper, v T) (Value, error), fromValue func(vm *GoLuaVmWrapper, v Value) (T, error)) {
+	var conv converter
+	if toValue != nil {
+		conv.toValue = func(vm *GoLuaVmWrapper, v reflect.Value) (Value, error) {
+			return toValue(vm, v.Interface().(T))
+		}
+	}
+	if fromValue != nil {
+		conv.fromValue = func(vm *GoLuaVmWrapper, v Value, target reflect.Value) error {
+			res, err := fromValue(vm, v)
+			if err != nil {
+				return err
+			}
+			target.Set(reflect.ValueOf(&res).Elem())
+			return nil
+		}
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	state := vm.state
+	state.convertersMu.Lock()
+	defer state.convertersMu.Unlock()
+	if state.converters == nil {
+		state.converters = map[reflect.Type]converter{}
+	}
+	state.converters[typ] = conv
+}
+
+// UnregisterConverter removes the converter registered for type T (if any).
+func UnregisterConverter[T any](vm *GoLuaVmWrapper) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	state := vm.state
+	state.convertersMu.Lock()
+	defer state.convertersMu.Unlock()
+	delete(state.converters, typ)
+}
+
+// converter returns the converter registered for typ
+func (s *vmState) converter(typ reflect.Type) (converter, bool) {
+	if s == nil {
+		return converter{}, false
+	}
+	s.convertersMu.RLock()
+	defer s.convertersMu.RUnlock()
+	conv, ok := s.converters[typ]
+	return conv, ok
+}
+
+// marshalCustom converts v using a registered converter or LuaMarshaler, if any.
+// owned is true if the conversion owns the returned value (see LuaValueRetainer).
+func (l *GoLuaVmWrapper) marshalCustom(v reflect.Value, path string) (val Value, handled, owned bool, err error) {
+	if conv, ok := l.vmState().converter(v.Type()); ok && conv.toValue != nil {
+		val, err := conv.toValue(l, v)
+		if err != nil {
+			return nil, true, false, pathError(path, "%w", err)
+		}
+		return nilIfUnset(val), true, true, nil
+	}
+
+	if !v.Type().Implements(marshalerType) {
+		if !v.CanAddr() || !reflect.PointerTo(v.Type()).Implements(marshalerType) {
+			return nil, false, false, nil
+		}
+		v = v.Addr()
+	}
+	if !v.CanInterface() || (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, false, false, nil
+	}
+
+	m := v.Interface().(LuaMarshaler)
+	val, err = m.MarshalLua(l)
+	if err != nil {
+		return nil, true, false, pathError(path, "%w", err)
+	}
+	return nilIfUnset(val), true, !retainsValue(m), nil
+}
+
+// unmarshalCustom decodes v into rv using a registered converter or LuaUnmarshaler, if any.
+// retained is true if the LuaUnmarshaler keeps v (see LuaValueRetainer).
+func (l *GoLuaVmWrapper) unmarshalCustom(v Value, rv reflect.Value, path string) (handled, retained bool, err error) {
+	if conv, ok := l.vmState().converter(rv.Type()); ok && conv.fromValue != nil {
+		if err := conv.fromValue(l, v, rv); err != nil {
+			return true, false, pathError(path, "%w", err)
+		}
+		return true, false, nil
+	}
+
+	if rv.Kind() == reflect.Pointer || !rv.CanAddr() || !rv.Addr().CanInterface() {
+		// Pointers are allocated by the caller and
+		// then decoded into like any other value
+		return false, false, nil
+	}
+	u, ok := rv.Addr().Interface().(LuaUnmarshaler)
+	if !ok {
+		return false, false, nil
+	}
+	if err := u.UnmarshalLua(l, v); err != nil {
+		return true, false, pathError(path, "%w", err)
+	}
+	return true, retainsValue(u), nil
+}
+
+// nilIfUnset returns a nil Value if v is nil
+func nilIfUnset(v Value) Value {
+	if v == nil {
+		return &ValueNil{}
+	}
+	return v
+}
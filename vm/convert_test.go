@@ -0,0 +1,76 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// tableRef marshals to (and unmarshals from) a Luau table it keeps a
+// reference to, retaining it if retain is set
+type tableRef struct {
+	tab    *vm.LuaTable
+	retain bool
+}
+
+func (r *tableRef) MarshalLua(v *vm.GoLuaVmWrapper) (vm.Value, error) {
+	tab, err := v.CreateTable()
+	if err != nil {
+		return nil, err
+	}
+	r.tab = tab
+	return tab.ToValue(), nil
+}
+
+func (r *tableRef) UnmarshalLua(_ *vm.GoLuaVmWrapper, v vm.Value) error {
+	if t, ok := v.(*vm.ValueTable); ok {
+		r.tab = t.Value()
+	}
+	return nil
+}
+
+func (r *tableRef) RetainsLuaValue() bool { return r.retain }
+
+// isOpen returns true if the table of r has not been closed
+func (r *tableRef) isOpen() bool {
+	_, err := r.tab.Len()
+	return err == nil
+}
+
+func TestMarshalerOwnership(t *testing.T) {
+	luaVm := newVm(t)
+
+	for _, retain := range []bool{false, true} {
+		holder := struct{ Ref *tableRef }{&tableRef{retain: retain}}
+		val, err := luaVm.ToValue(holder)
+		if err != nil {
+			t.Fatalf("ToValue: %v", err)
+		}
+		val.Close()
+
+		// The returned value is closed by the conversion unless retained
+		if got := holder.Ref.isOpen(); got != retain {
+			t.Errorf("retain=%v: table open = %v", retain, got)
+		}
+		holder.Ref.tab.Close()
+	}
+}
+
+func TestUnmarshalerOwnership(t *testing.T) {
+	luaVm := newVm(t)
+
+	for _, retain := range []bool{false, true} {
+		rets := exec(t, luaVm, `return { Ref = {} }`)
+		holder := struct{ Ref tableRef }{tableRef{retain: retain}}
+		if err := luaVm.FromValue(rets[0], &holder); err != nil {
+			t.Fatalf("FromValue: %v", err)
+		}
+		rets[0].Close()
+
+		// The decoded value is closed by the conversion unless retained
+		if got := holder.Ref.isOpen(); got != retain {
+			t.Errorf("retain=%v: table open = %v", retain, got)
+		}
+		holder.Ref.tab.Close()
+	}
+}
@@ -0,0 +1,201 @@
+package vm
+
+import (
+	"errors"
+	"runtime/cgo"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// LuaErrorKind is the kind of a LuaError
+type LuaErrorKind int
+
+const (
+	LuaErrorOther        LuaErrorKind = 0 // Any other error
+	LuaErrorSyntax       LuaErrorKind = 1 // Syntax error while compiling a chunk
+	LuaErrorRuntime      LuaErrorKind = 2 // Runtime error raised by Luau code
+	LuaErrorMemory       LuaErrorKind = 3 // The Lua VM ran out of memory (or hit its memory limit)
+	LuaErrorCallback     LuaErrorKind = 4 // Error raised by a Go callback
+	LuaErrorClosedObject LuaErrorKind = 5 // A closed object (or VM) was used
+)
+
+func (k LuaErrorKind) String() string {
+	switch k {
+	case LuaErrorSyntax:
+		return "syntax"
+	case LuaErrorRuntime:
+		return "runtime"
+	case LuaErrorMemory:
+		return "memory"
+	case LuaErrorCallback:
+		return "callback"
+	case LuaErrorClosedObject:
+		return "closed object"
+	default:
+		return "other"
+	}
+}
+
+var (
+	// ErrClosed is matched (using errors.Is) by errors caused by
+	// using a closed object or VM
+	ErrClosed = errors.New("cannot use closed object")
+	// ErrMemory is matched (using errors.Is) by errors caused by
+	// the Lua VM running out of memory
+	ErrMemory = errors.New("not enough memory")
+)
+
+// LuaError is the error returned by operations on the Lua VM.
+//
+// Use errors.As to access the details of an error:
+//
+//	var luaErr *vm.LuaError
+//	if errors.As(err, &luaErr) && luaErr.Kind == vm.LuaErrorSyntax {
+//		fmt.Printf("syntax error in %s at line %d\n", luaErr.Chunk, luaErr.Line)
+//	}
+type LuaError struct {
+	Kind LuaErrorKind
+	// The error message (including the location, as reported by Luau)
+	Message string
+	// Name of the chunk the error occurred in, if known
+	Chunk string
+	// Line the error occurred at, 0 if unknown
+	Line int
+	// The Luau stack traceback, if any
+	Traceback string
+
+	// The error returned by the Go callback that raised
+	// this error (for LuaErrorCallback errors)
+	cause error
+	// The value the error was raised with (see Value)
+	value Value
+	// Program counters of the Go stack of the Go callback that
+	// raised this error (see StackTrace)
+	pcs []uintptr
+}
+
+func (e *LuaError) Error() string {
+	switch e.Kind {
+	case LuaErrorSyntax, LuaErrorRuntime, LuaErrorMemory, LuaErrorCallback:
+		return e.Kind.String() + " error: " + e.Message
+	default:
+		return e.Message
+	}
+}
+
+// Value returns the value the error was raised with by Luau code, such
+// as the table passed to error() in
+//
+//	error({code = 404, message = "missing"})
+//
+// which can then be decoded using FromValue.
+//
+// Value returns nil for errors that were not raised with a Luau value
+// (such as syntax errors). Only errors returned by LuaFunction.Call (and
+// the other call methods) carry a value, errors from e.g. table accesses
+// that invoke metamethods do not.
+func (e *LuaError) Value() Value {
+	return e.value
+}
+
+// Unwrap returns the error returned by the Go callback that raised
+// the error, or the sentinel error matching the kind of the error (if any).
+//
+// Errors returned by Go callbacks keep their identity when passing
+// through Luau code (including pcall and error), so errors.Is and
+// errors.As can be used to check for them:
+//
+//	_, err := fn.Call(nil)
+//	if errors.Is(err, ErrNotFound) {
+//		...
+//	}
+func (e *LuaError) Unwrap() error {
+	if e.cause != nil {
+		return e.cause
+	}
+	switch e.Kind {
+	case LuaErrorMemory:
+		return ErrMemory
+	case LuaErrorClosedObject:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
+
+// errClosedObject returns the error for using a closed object
+func errClosedObject() error {
+	return &LuaError{Kind: LuaErrorClosedObject, Message: ErrClosed.Error()}
+}
+
+// errorFieldSeparator separates the fields of errors encoded by the Rust side
+const errorFieldSeparator = "\x1e"
+
+// parseLuaError parses an error sent by the Rust side into a LuaError.
+//
+// Errors from mluau are encoded as
+// `\x1e<kind>\x1e<chunk>\x1e<line>\x1e<traceback>\x1e<go error>\x1e<message>`,
+// other errors are plain messages. <go error> is the handle of the
+// callback holding the Go error (see newGoErrorCallback) or 0.
+func parseLuaError(s string) *LuaError {
+	if !strings.HasPrefix(s, errorFieldSeparator) {
+		return &LuaError{Kind: LuaErrorOther, Message: s}
+	}
+
+	fields := strings.SplitN(s[len(errorFieldSeparator):], errorFieldSeparator, 6)
+	if len(fields) != 6 {
+		return &LuaError{Kind: LuaErrorOther, Message: s}
+	}
+
+	kind, _ := strconv.Atoi(fields[0])
+	line, _ := strconv.Atoi(fields[2])
+	goErrorHandle, _ := strconv.ParseUint(fields[4], 10, 64)
+	goErr := goErrorFromHandle(goErrorHandle)
+	return &LuaError{
+		Kind:      LuaErrorKind(kind),
+		Chunk:     fields[1],
+		Line:      line,
+		Traceback: fields[3],
+		Message:   fields[5],
+		cause:     goErr.err,
+		pcs:       goErr.pcs,
+	}
+}
+
+// goError is an error returned by a Go callback
+type goError struct {
+	err error
+	// Program counters of the stack of the callback
+	pcs []uintptr
+}
+
+// newGoErrorCallback returns the callback passed to Rust along with the
+// message of an error returned by a Go callback.
+//
+// The callback holds err until Rust drops it (once the GoError userdata
+// raised for err is collected). Rust never calls it, instead Go calls
+// it with a *goError to take err out (see goErrorFromHandle).
+func newGoErrorCallback(err error, pcs []uintptr) *goCallback {
+	return newGoCallback(func(val unsafe.Pointer) {
+		*(*goError)(val) = goError{err: err, pcs: pcs}
+	}, nil)
+}
+
+// goErrorFromHandle returns the Go error held by the callback
+// (see newGoErrorCallback) with the given handle.
+//
+// The GoError userdata owning the callback must still be alive,
+// which the Rust side ensures by passing it along with the error.
+func goErrorFromHandle(handle uint64) goError {
+	if handle == 0 {
+		return goError{}
+	}
+	cb, ok := cgo.Handle(handle).Value().(*goCallback)
+	if !ok || cb.handle == nil {
+		return goError{}
+	}
+	var goErr goError
+	cb.handle(unsafe.Pointer(&goErr))
+	return goErr
+}
@@ -0,0 +1,165 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+var errSentinel = errors.New("sentinel")
+
+// setFailing sets the global fail to a function returning errSentinel
+func setFailing(t *testing.T, luaVm *vm.GoLuaVmWrapper) {
+	t.Helper()
+	fn, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		return nil, errSentinel
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if err := luaVm.SetGlobal("fail", fn.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+}
+
+func TestGoErrorIdentity(t *testing.T) {
+	luaVm := newVm(t)
+	setFailing(t, luaVm)
+
+	// The Go error survives being caught and re-raised by Luau
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `
+		local ok, err = pcall(fail)
+		assert(not ok)
+		error(err)
+	`})
+	if !errors.Is(err, errSentinel) {
+		t.Fatalf("err = %v, want errSentinel", err)
+	}
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) || luaErr.Kind != vm.LuaErrorCallback {
+		t.Fatalf("err = %#v, want callback LuaError", err)
+	}
+}
+
+func TestGoErrorString(t *testing.T) {
+	luaVm := newVm(t)
+	setFailing(t, luaVm)
+
+	rets := exec(t, luaVm, `
+		local _, err = pcall(fail)
+		return tostring(err), err.message, "failed: " .. err, err:match("sent(%a+)"), #err
+	`)
+	want := []string{"sentinel", "sentinel", "failed: sentinel", "inel"}
+	for i, w := range want {
+		if got := decode[string](t, luaVm, rets[i]); got != w {
+			t.Errorf("ret %d = %q, want %q", i, got, w)
+		}
+	}
+	if got := decode[int](t, luaVm, rets[4]); got != len("sentinel") {
+		t.Errorf("#err = %d, want %d", got, len("sentinel"))
+	}
+}
+
+func TestGoErrorXpcallReassigned(t *testing.T) {
+	luaVm := newVm(t)
+	setFailing(t, luaVm)
+
+	// Protected calls from Go use the xpcall captured at VM creation
+	exec(t, luaVm, `xpcall = nil; pcall = nil`)
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `fail()`})
+	if !errors.Is(err, errSentinel) {
+		t.Fatalf("err = %v, want errSentinel", err)
+	}
+}
+
+func TestLuaErrorValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `error({ code = 404, message = "missing" })`})
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	var payload struct {
+		Code    int    `lua:"code"`
+		Message string `lua:"message"`
+	}
+	if err := luaVm.FromValue(luaErr.Value(), &payload); err != nil {
+		t.Fatalf("FromValue: %v", err)
+	}
+	if payload.Code != 404 || payload.Message != "missing" {
+		t.Fatalf("payload = %+v", payload)
+	}
+
+	// String errors are kept as raised (without the location)
+	fn := loadFunc(t, luaVm, `return function() error("plain", 0) end`)
+	defer fn.Close()
+	_, err = fn.Call(nil)
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	if got := decode[string](t, luaVm, luaErr.Value()); got != "plain" {
+		t.Fatalf("Value = %q, want plain", got)
+	}
+
+	// Syntax errors carry no value
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `local = 1`})
+	if !errors.As(err, &luaErr) || luaErr.Kind != vm.LuaErrorSyntax {
+		t.Fatalf("err = %#v, want syntax LuaError", err)
+	}
+	if luaErr.Value() != nil {
+		t.Fatalf("Value of a syntax error = %#v, want nil", luaErr.Value())
+	}
+}
+
+func TestLuaErrorFields(t *testing.T) {
+	luaVm := newVm(t)
+
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "script", Code: "local x = 1\nerror('bad')"})
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	if luaErr.Kind != vm.LuaErrorRuntime {
+		t.Errorf("Kind = %v, want runtime", luaErr.Kind)
+	}
+	if !strings.Contains(luaErr.Chunk, "script") || luaErr.Line != 2 {
+		t.Errorf("location = %q:%d, want script:2", luaErr.Chunk, luaErr.Line)
+	}
+	if !strings.Contains(luaErr.Message, "bad") || luaErr.Traceback == "" {
+		t.Errorf("Message = %q, Traceback = %q", luaErr.Message, luaErr.Traceback)
+	}
+	if !strings.HasPrefix(err.Error(), "runtime error: ") {
+		t.Errorf("Error() = %q, want the kind prefix", err.Error())
+	}
+
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "script", Code: "x ="})
+	if !errors.As(err, &luaErr) || luaErr.Kind != vm.LuaErrorSyntax {
+		t.Errorf("err = %#v, want syntax LuaError", err)
+	}
+
+	if err := luaVm.SetMemoryLimit(256 << 10); err != nil {
+		t.Fatalf("SetMemoryLimit: %v", err)
+	}
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "script", Code: `local t = {} for i = 1, 1e7 do t[i] = i end`})
+	if !errors.Is(err, vm.ErrMemory) {
+		t.Errorf("err = %v, want ErrMemory", err)
+	}
+}
+
+func TestLuaErrorClosed(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+	luaVm.Close()
+
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "script", Code: `return 1`})
+	var luaErr *vm.LuaError
+	if !errors.Is(err, vm.ErrClosed) || !errors.As(err, &luaErr) || luaErr.Kind != vm.LuaErrorClosedObject {
+		t.Fatalf("err = %#v, want closed object LuaError", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 import "C"
 
 var errorVariantTab = objectTab{
+	name: "ErrorVariant",
 	dtor: func(ptr *C.void) {
 		C.luago_error_free((*C.struct_ErrorVariant)(unsafe.Pointer(ptr)))
 	},
@@ -0,0 +1,236 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrExecutorClosed is returned by Do/Go once the Lua VM has been closed
+var ErrExecutorClosed = errors.New("cannot submit work to closed Lua VM")
+
+// ExecutorFn is a unit of work run by the executor of a Lua VM
+type ExecutorFn = func(vm *GoLuaVmWrapper) error
+
+type executorWork struct {
+	fn     ExecutorFn
+	result chan error
+}
+
+// executor owns a dedicated goroutine that runs all work submitted
+// through Do/Go one at a time.
+//
+// Work is queued without blocking, so submitting never waits for
+// the executor goroutine (which may itself be submitting work).
+type executor struct {
+	mu      sync.Mutex
+	closed  bool
+	aborted bool // Stopped from executor work, queued work is not run
+	queue   []executorWork
+	wake    chan struct{} // Signalled when work is queued or the executor is stopped
+	done    chan struct{}
+	gid     atomic.Uint64 // Id of the executor goroutine
+}
+
+func newExecutor(vm *GoLuaVmWrapper) *executor {
+	e := &executor{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go e.run(vm)
+	return e
+}
+
+func (e *executor) run(vm *GoLuaVmWrapper) {
+	// Luau itself does not care which OS thread runs it, but pinning the
+	// goroutine keeps thread-local state of C libraries consistent
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(e.done)
+	e.gid.Store(goroutineID())
+
+	for {
+		w, ok := e.next()
+		if !ok {
+			return
+		}
+		err := e.call(vm, w.fn)
+		if e.isAborted() {
+			// Closed from the work, which may still have been using
+			// the Lua VM (see GoLuaVmWrapper.Close)
+			vm.obj.Close()
+		}
+		w.result <- err
+	}
+}
+
+// next waits for the next unit of work, returning false once the
+// executor is stopped and all queued work has been handled
+func (e *executor) next() (executorWork, bool) {
+	for {
+		e.mu.Lock()
+		if len(e.queue) > 0 && !e.aborted {
+			w := e.queue[0]
+			e.queue[0] = executorWork{}
+			e.queue = e.queue[1:]
+			e.mu.Unlock()
+			return w, true
+		}
+		if e.closed {
+			pending := e.queue
+			e.queue = nil
+			e.mu.Unlock()
+			for _, w := range pending {
+				w.result <- ErrExecutorClosed
+			}
+			return executorWork{}, false
+		}
+		e.mu.Unlock()
+		<-e.wake
+	}
+}
+
+func (e *executor) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default: // Already signalled
+	}
+}
+
+func (e *executor) call(vm *GoLuaVmWrapper, fn ExecutorFn) (err error) {
+	// A panic in submitted work must not kill the executor goroutine
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in executor work: %v", r)
+		}
+	}()
+	return fn(vm)
+}
+
+// onExecutor returns true if called from the executor goroutine
+// (that is, from executor work)
+func (e *executor) onExecutor() bool {
+	return e != nil && e.gid.Load() == goroutineID()
+}
+
+func (e *executor) isAborted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.aborted
+}
+
+func (e *executor) isClosed() bool {
+	if e == nil {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.closed
+}
+
+func (e *executor) submit(fn ExecutorFn) <-chan error {
+	result := make(chan error, 1)
+	if e == nil {
+		// The Lua VM was closed before the executor was ever started
+		result <- ErrExecutorClosed
+		return result
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		result <- ErrExecutorClosed
+		return result
+	}
+	e.queue = append(e.queue, executorWork{fn: fn, result: result})
+	e.mu.Unlock()
+	e.signal()
+	return result
+}
+
+// stop stops the executor once all queued work has run, waiting for it.
+//
+// When called from executor work, stop returns immediately and work
+// queued after the current one fails with ErrExecutorClosed instead. The
+// executor then closes the Lua VM once the current work returns, and stop
+// returns true.
+func (e *executor) stop() bool {
+	inside := e.onExecutor()
+
+	e.mu.Lock()
+	if e.closed {
+		// Closing again from the work that stopped the executor
+		// must not close the Lua VM before the work returns
+		deferred := inside && e.aborted
+		e.mu.Unlock()
+		return deferred
+	}
+	e.closed = true
+	e.aborted = inside
+	e.mu.Unlock()
+	e.signal()
+
+	if !inside {
+		<-e.done
+	}
+	return inside
+}
+
+// goroutineID returns the id of the calling goroutine
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	// The trace starts with "goroutine <id> [<status>]:"
+	s := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	id, _ := strconv.ParseUint(string(s), 10, 64)
+	return id
+}
+
+func (l *GoLuaVmWrapper) executor() *executor {
+	l.state.executorOnce.Do(func() {
+		// Callback VMs are closed once their callback returns, so
+		// the executor must use the wrapper owning the Lua VM
+		l.state.executor = newExecutor(l.owner())
+	})
+	return l.state.executor
+}
+
+// Do runs fn on the executor goroutine of the Lua VM and waits for it to return.
+//
+// The first call to Do or Go starts a dedicated goroutine that owns the Lua VM
+// and runs all submitted work one at a time, so multiple goroutines can safely
+// share the Lua VM through Do/Go without any locking of their own. Once in
+// executor mode, the Lua VM should only be used through Do/Go.
+//
+// fn must use the vm passed to it. Calling Do from within fn (or a Go callback
+// called by Luau during fn) runs the nested fn immediately instead of queueing it.
+// Closing the Lua VM from within fn (or a Go callback called during fn) stops
+// the executor and closes the Lua VM once fn returns, failing any work still
+// queued with ErrExecutorClosed. Waiting on the channel returned
+// by Go from within fn will deadlock, as the work cannot run until fn returns.
+func (l *GoLuaVmWrapper) Do(fn ExecutorFn) error {
+	e := l.executor()
+	if e.onExecutor() {
+		// Queueing the work would wait for the work running it
+		if e.isClosed() {
+			return ErrExecutorClosed
+		}
+		return e.call(l, fn)
+	}
+	return <-e.submit(fn)
+}
+
+// Go submits fn to the executor goroutine of the Lua VM without waiting for it
+// to run (Go never blocks). The returned channel receives the error returned by fn.
+//
+// See Do for more information.
+func (l *GoLuaVmWrapper) Go(fn ExecutorFn) <-chan error {
+	return l.executor().submit(fn)
+}
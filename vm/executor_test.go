@@ -0,0 +1,222 @@
+package vm_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// waitErr waits for the result of work submitted through Go, failing
+// the test if it does not arrive in time (instead of deadlocking)
+func waitErr(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for executor work")
+		return nil
+	}
+}
+
+func TestExecutorDo(t *testing.T) {
+	luaVm := newVm(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := luaVm.Do(func(v *vm.GoLuaVmWrapper) error {
+				_, err := v.ExecChunk(vm.ChunkOpts{Name: "test", Code: `counter = (counter or 0) + 1`})
+				return err
+			})
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := luaVm.Do(func(v *vm.GoLuaVmWrapper) error {
+		rets, err := v.ExecChunk(vm.ChunkOpts{Name: "test", Code: `return counter`})
+		if err != nil {
+			return err
+		}
+		if got := decode[int](t, v, rets[0]); got != 8 {
+			t.Errorf("counter = %d, want 8", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+}
+
+func TestExecutorPanic(t *testing.T) {
+	luaVm := newVm(t)
+
+	err := luaVm.Do(func(*vm.GoLuaVmWrapper) error { panic("boom") })
+	if err == nil {
+		t.Fatal("Do with panicking work succeeded")
+	}
+	// The executor keeps running
+	if err := luaVm.Do(func(*vm.GoLuaVmWrapper) error { return nil }); err != nil {
+		t.Fatalf("Do after panic: %v", err)
+	}
+}
+
+func TestExecutorReentrant(t *testing.T) {
+	luaVm := newVm(t)
+	errInner := errors.New("inner")
+
+	var queued <-chan error
+	err := luaVm.Do(func(v *vm.GoLuaVmWrapper) error {
+		// Nested Do runs immediately
+		if err := v.Do(func(*vm.GoLuaVmWrapper) error { return errInner }); !errors.Is(err, errInner) {
+			t.Errorf("nested Do = %v, want errInner", err)
+		}
+
+		// Nested Do from a Go callback called by Luau
+		fn, err := v.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, _ []vm.Value) ([]vm.Value, error) {
+			return nil, funcVm.Do(func(*vm.GoLuaVmWrapper) error { return nil })
+		})
+		if err != nil {
+			return err
+		}
+		defer fn.Close()
+		if _, err := fn.Call(nil); err != nil {
+			t.Errorf("Do from callback: %v", err)
+		}
+
+		// Go does not block (nor run the work) while work is running
+		queued = v.Go(func(*vm.GoLuaVmWrapper) error { return errInner })
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if err := waitErr(t, queued); !errors.Is(err, errInner) {
+		t.Fatalf("queued work = %v, want errInner", err)
+	}
+}
+
+func TestExecutorCloseFromWork(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+
+	var queued <-chan error
+	done := luaVm.Go(func(v *vm.GoLuaVmWrapper) error {
+		queued = v.Go(func(*vm.GoLuaVmWrapper) error { return nil })
+		luaVm.Close()
+		return nil
+	})
+	if err := waitErr(t, done); err != nil {
+		t.Fatalf("work closing the VM: %v", err)
+	}
+	if err := waitErr(t, queued); !errors.Is(err, vm.ErrExecutorClosed) {
+		t.Fatalf("work queued before close = %v, want ErrExecutorClosed", err)
+	}
+	if err := luaVm.Do(func(*vm.GoLuaVmWrapper) error { return nil }); !errors.Is(err, vm.ErrExecutorClosed) {
+		t.Fatalf("Do after close = %v, want ErrExecutorClosed", err)
+	}
+}
+
+func TestExecutorCloseRunsQueued(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+
+	release := make(chan struct{})
+	first := luaVm.Go(func(*vm.GoLuaVmWrapper) error {
+		<-release
+		return nil
+	})
+	second := luaVm.Go(func(*vm.GoLuaVmWrapper) error { return nil })
+
+	closed := make(chan struct{})
+	go func() {
+		luaVm.Close()
+		close(closed)
+	}()
+	close(release)
+
+	// Work submitted before Close still runs
+	if err := waitErr(t, first); err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	if err := waitErr(t, second); err != nil {
+		t.Fatalf("second: %v", err)
+	}
+	<-closed
+}
+
+func TestExecutorStartedFromCallback(t *testing.T) {
+	luaVm := newVm(t)
+
+	// The first submission comes from a callback VM, which is closed
+	// once the callback returns
+	var queued <-chan error
+	fn, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, _ []vm.Value) ([]vm.Value, error) {
+		queued = funcVm.Go(func(v *vm.GoLuaVmWrapper) error {
+			_, err := v.ExecChunk(vm.ChunkOpts{Name: "queued", Code: `return 1`})
+			return err
+		})
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer fn.Close()
+	if _, err := fn.Call(nil); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if err := waitErr(t, queued); err != nil {
+		t.Fatalf("work submitted from a callback: %v", err)
+	}
+
+	// Later work still runs on the Lua VM
+	err = luaVm.Do(func(v *vm.GoLuaVmWrapper) error {
+		_, err := v.ExecChunk(vm.ChunkOpts{Name: "later", Code: `return 1`})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do after the callback returned: %v", err)
+	}
+}
+
+func TestExecutorCloseFromCallback(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+
+	done := luaVm.Go(func(v *vm.GoLuaVmWrapper) error {
+		fn, err := v.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+			// The Lua VM is still in use by the call running this callback
+			luaVm.Close()
+			return nil, nil
+		})
+		if err != nil {
+			return err
+		}
+		defer fn.Close()
+		_, err = fn.Call(nil)
+		return err
+	})
+	if err := waitErr(t, done); err != nil {
+		t.Fatalf("work closing the VM from a callback: %v", err)
+	}
+	if err := luaVm.Do(func(*vm.GoLuaVmWrapper) error { return nil }); !errors.Is(err, vm.ErrExecutorClosed) {
+		t.Fatalf("Do after close = %v, want ErrExecutorClosed", err)
+	}
+	if _, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `return 1`}); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("ExecChunk after close = %v, want ErrClosed", err)
+	}
+}
@@ -4,9 +4,15 @@ package vm
 #include "../rustlib/rustlib.h"
 */
 import "C"
-import "unsafe"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+)
 
 var functionTab = objectTab{
+	name: "LuaFunction",
 	dtor: func(ptr *C.void) {
 		C.luago_free_function((*C.struct_LuaFunction)(unsafe.Pointer(ptr)))
 	},
@@ -29,9 +35,109 @@ func (l *LuaFunction) innerPtr() (*C.struct_LuaFunction, error) {
 // Call calls a function `f` returning either the returned arguments
 // or the error
 func (l *LuaFunction) Call(args []Value) ([]Value, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
+	rets, _, err := l.callMetered(context.Background(), args)
+	return rets, err
+}
+
+// CallMetered calls a function `f` like Call, additionally returning the
+// number of interrupt ticks the call used.
+//
+// See GoLuaVmWrapper.SetInstructionBudget for more information on metering.
+func (l *LuaFunction) CallMetered(args []Value) ([]Value, uint64, error) {
+	defer l.lua.vmState().repanic()
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	return l.callMetered(context.Background(), args)
+}
+
+// CallContext calls a function `f` like Call, but stops the function
+// once ctx is cancelled or its deadline is exceeded.
+//
+// The function is stopped at the next interrupt point (loop iteration
+// or function call) of the Luau code, so this also stops scripts
+// stuck in infinite loops. The returned error then wraps ctx.Err().
+// Only this call (and the calls made by the Go callbacks it calls) is
+// stopped, calls made concurrently from other goroutines keep running.
+//
+// Note that Go callbacks called by the function are not interrupted,
+// but can observe ctx using GoLuaVmWrapper.Context.
+func (l *LuaFunction) CallContext(ctx context.Context, args []Value) ([]Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("luau execution interrupted: %w", err)
+	}
+
+	defer l.lua.vmState().repanic()
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	restore := l.lua.vmState().enterContext(ctx)
+	defer restore()
+
+	rets, _, err := l.callMetered(ctx, args)
+	return rets, err
+}
+
+// callMetered calls the function while enforcing the instruction budget
+// of the Lua VM and ctx. The caller must hold the read lock
+func (l *LuaFunction) callMetered(ctx context.Context, args []Value) ([]Value, uint64, error) {
+	var rets []Value
+	used, err := l.lua.meter(ctx, func(call *C.struct_CallState) error {
+		var err error
+		rets, err = l.call(args, nil, call)
+		return err
+	})
+	if err != nil {
+		return nil, used, err
+	}
+	return rets, used, nil
+}
+
+// CallWithHandler calls a function `f` like Call, calling handler
+// if the function raises an error.
+//
+// As with the message handler of Luau's xpcall, handler is called with the
+// error value at the point of the error (before the stack is unwound), so
+// it can capture the traceback (e.g. using debug.traceback) and other
+// context of the error. The value it returns replaces the error value:
+// it is returned by LuaError.Value and used as the error message.
+//
+// handler may also be a Go function created with CreateFunction.
+func (l *LuaFunction) CallWithHandler(args []Value, handler *LuaFunction) ([]Value, error) {
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+
+	defer l.lua.vmState().repanic()
+	l.object.RLock()
+	defer l.object.RUnlock()
+	handler.object.RLock()
+	defer handler.object.RUnlock()
+
+	handlerPtr, err := handler.innerPtr()
+	if err != nil {
+		return nil, err // Return error if the handler is closed
+	}
+
+	var rets []Value
+	_, err = l.lua.meter(context.Background(), func(call *C.struct_CallState) error {
+		var err error
+		rets, err = l.call(args, handlerPtr, call)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rets, nil
+}
+
+// call calls the function, with a message handler if handler is
+// not nil, as part of call. The caller must hold the read lock
+func (l *LuaFunction) call(args []Value, handler *C.struct_LuaFunction, call *C.struct_CallState) ([]Value, error) {
 	ptr, err := l.innerPtr()
 	if err != nil {
 		return nil, err // Return error if the object is closed
@@ -41,11 +147,41 @@ func (l *LuaFunction) Call(args []Value) ([]Value, error) {
 		return nil, err // Return error if the value cannot be converted
 	}
 
-	res := C.luago_function_call(ptr, mw.ptr)
+	// Functions owned by a closed (callback) VM cannot be called,
+	// as the context and budget of the call could not be enforced
+	l.lua.obj.RLock()
+	defer l.lua.obj.RUnlock()
+	lua, err := l.lua.lua()
+	if err != nil {
+		mw.close()
+		return nil, err
+	}
+
+	var res C.struct_GoMultiValueResult
+	if handler != nil {
+		res = C.luago_function_call_with_handler(lua, ptr, handler, mw.ptr, call)
+	} else {
+		res = C.luago_function_call(lua, ptr, mw.ptr, call)
+	}
+	return l.lua.takeCallResult(res)
+}
+
+// takeCallResult takes the values returned by a protected call (see
+// protect.rs), or its error along with the value it was raised with
+func (l *GoLuaVmWrapper) takeCallResult(res C.struct_GoMultiValueResult) ([]Value, error) {
 	if res.error != nil {
-		return nil, moveErrorToGoError(res.error)
+		luaErr := parseLuaError(moveErrorToGo(res.error))
+		if res.value != nil {
+			// The value the error was raised with
+			errMw := &luaMultiValue{ptr: res.value, lua: l}
+			if vals := errMw.take(); len(vals) > 0 {
+				luaErr.value = vals[0]
+			}
+			errMw.close()
+		}
+		return nil, luaErr
 	}
-	rets := &luaMultiValue{ptr: res.value}
+	rets := &luaMultiValue{ptr: res.value, lua: l}
 	retsMw := rets.take()
 	rets.close()
 	return retsMw, nil
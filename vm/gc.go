@@ -0,0 +1,137 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+
+// GCIncParams represents the parameters of the incremental garbage collector.
+//
+// Fields left as 0 keep their current value.
+type GCIncParams struct {
+	// The GC goal (in percent).
+	//
+	// This is the target heap size relative to the live heap
+	// size after a collection cycle (e.g. 200 = twice the live size)
+	Goal int
+	// The GC step multiplier (in percent).
+	//
+	// This controls how much work each step does relative to
+	// the amount of memory allocated since the last step
+	StepMultiplier int
+	// The GC step size (in KB)
+	StepSize int
+}
+
+// Converts GCIncParams to C struct
+func (p *GCIncParams) toC() C.struct_GcIncParams {
+	return C.struct_GcIncParams{
+		goal:            C.int32_t(p.Goal),
+		step_multiplier: C.int32_t(p.StepMultiplier),
+		step_size:       C.int32_t(p.StepSize),
+	}
+}
+
+// UsedMemory returns the amount of memory (in bytes) currently used by the Lua VM.
+func (l *GoLuaVmWrapper) UsedMemory() int {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return 0 // A closed VM uses no memory
+	}
+	return int(C.luavm_used_memory(lua))
+}
+
+// MemoryLimit returns the memory limit set by SetMemoryLimit.
+//
+// A limit of 0 means that there is no limit.
+func (l *GoLuaVmWrapper) MemoryLimit() int {
+	return int(l.state.memoryLimit.Load())
+}
+
+// GCCollect performs a full garbage collection cycle.
+func (l *GoLuaVmWrapper) GCCollect() error {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+	res := C.luavm_gc_collect(lua)
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// GCStep performs a incremental garbage collection step
+// of roughly kb kilobytes.
+//
+// Returns true if the step finished a collection cycle.
+func (l *GoLuaVmWrapper) GCStep(kb int) (bool, error) {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return false, err
+	}
+	res := C.luavm_gc_step(lua, C.int32_t(kb))
+	if res.error != nil {
+		return false, moveErrorToGoError(res.error)
+	}
+	return bool(res.value), nil
+}
+
+// GCStop stops the garbage collector until GCRestart is called.
+//
+// Memory is only reclaimed by explicit calls to GCCollect/GCStep while stopped.
+func (l *GoLuaVmWrapper) GCStop() {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return // No-op if the Lua VM is closed
+	}
+	C.luavm_gc_stop(lua)
+}
+
+// GCRestart restarts a garbage collector stopped by GCStop.
+func (l *GoLuaVmWrapper) GCRestart() {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return // No-op if the Lua VM is closed
+	}
+	C.luavm_gc_restart(lua)
+}
+
+// GCIsRunning returns true if the garbage collector is running (not stopped).
+func (l *GoLuaVmWrapper) GCIsRunning() bool {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return false
+	}
+	return bool(C.luavm_gc_is_running(lua))
+}
+
+// GCSetIncremental tunes the parameters of the incremental garbage collector.
+func (l *GoLuaVmWrapper) GCSetIncremental(params GCIncParams) {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return // No-op if the Lua VM is closed
+	}
+	C.luavm_gc_set_incremental(lua, params.toC())
+}
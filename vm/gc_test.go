@@ -0,0 +1,93 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestMemoryStats(t *testing.T) {
+	luaVm := newVm(t)
+
+	if luaVm.MemoryLimit() != 0 {
+		t.Fatalf("MemoryLimit = %d, want 0 (no limit)", luaVm.MemoryLimit())
+	}
+	if err := luaVm.SetMemoryLimit(64 << 20); err != nil {
+		t.Fatalf("SetMemoryLimit: %v", err)
+	}
+	if luaVm.MemoryLimit() != 64<<20 {
+		t.Fatalf("MemoryLimit = %d, want %d", luaVm.MemoryLimit(), 64<<20)
+	}
+
+	before := luaVm.UsedMemory()
+	if before <= 0 {
+		t.Fatalf("UsedMemory = %d, want > 0", before)
+	}
+	exec(t, luaVm, `garbage = {} for i = 1, 10000 do garbage[i] = { i } end`)
+	if used := luaVm.UsedMemory(); used <= before {
+		t.Fatalf("UsedMemory after allocating = %d, want > %d", used, before)
+	}
+
+	// Dropping the reference and collecting frees the memory again
+	exec(t, luaVm, `garbage = nil`)
+	peak := luaVm.UsedMemory()
+	if err := luaVm.GCCollect(); err != nil {
+		t.Fatalf("GCCollect: %v", err)
+	}
+	if used := luaVm.UsedMemory(); used >= peak {
+		t.Fatalf("UsedMemory after GCCollect = %d, want < %d", used, peak)
+	}
+}
+
+func TestGCControls(t *testing.T) {
+	luaVm := newVm(t)
+
+	if !luaVm.GCIsRunning() {
+		t.Fatal("GC is not running on a new VM")
+	}
+	luaVm.GCStop()
+	if luaVm.GCIsRunning() {
+		t.Fatal("GC is running after GCStop")
+	}
+	luaVm.GCRestart()
+	if !luaVm.GCIsRunning() {
+		t.Fatal("GC is not running after GCRestart")
+	}
+
+	luaVm.GCSetIncremental(vm.GCIncParams{Goal: 150, StepMultiplier: 200, StepSize: 8})
+	exec(t, luaVm, `for i = 1, 1000 do local _ = { i } end`)
+
+	// Stepping repeatedly eventually finishes a cycle
+	finished := false
+	for i := 0; i < 10000 && !finished; i++ {
+		var err error
+		if finished, err = luaVm.GCStep(64); err != nil {
+			t.Fatalf("GCStep: %v", err)
+		}
+	}
+	if !finished {
+		t.Fatal("GCStep never finished a collection cycle")
+	}
+}
+
+func TestGCClosed(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+	luaVm.Close()
+
+	if used := luaVm.UsedMemory(); used != 0 {
+		t.Fatalf("UsedMemory of a closed VM = %d, want 0", used)
+	}
+	if err := luaVm.GCCollect(); err == nil {
+		t.Fatal("GCCollect on a closed VM succeeded")
+	}
+	if _, err := luaVm.GCStep(1); err == nil {
+		t.Fatal("GCStep on a closed VM succeeded")
+	}
+	// The remaining controls are no-ops
+	luaVm.GCStop()
+	luaVm.GCRestart()
+	luaVm.GCSetIncremental(vm.GCIncParams{})
+}
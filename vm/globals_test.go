@@ -0,0 +1,67 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestGlobals(t *testing.T) {
+	luaVm := newVm(t)
+
+	if err := luaVm.SetGlobal("answer", vm.NewValueInteger(42)); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	// The standard library stays available next to the new global
+	rets := exec(t, luaVm, `return string.format("%d", answer), missing`)
+	if got := decode[string](t, luaVm, rets[0]); got != "42" {
+		t.Fatalf("answer = %q, want 42", got)
+	}
+	if rets[1].Type() != vm.LuaValueNil {
+		t.Fatalf("missing = %v, want nil", rets[1].Type())
+	}
+
+	exec(t, luaVm, `fromLua = "hi"`)
+	v, err := luaVm.GetGlobal("fromLua")
+	if err != nil {
+		t.Fatalf("GetGlobal: %v", err)
+	}
+	if got := decode[string](t, luaVm, v); got != "hi" {
+		t.Fatalf("fromLua = %q, want hi", got)
+	}
+	v, err = luaVm.GetGlobal("nothing")
+	if err != nil {
+		t.Fatalf("GetGlobal: %v", err)
+	}
+	if v.Type() != vm.LuaValueNil {
+		t.Fatalf("nothing = %v, want nil", v.Type())
+	}
+
+	globals := luaVm.Globals()
+	if globals == nil {
+		t.Fatal("Globals returned nil")
+	}
+	defer globals.Close()
+	ok, err := globals.ContainsKey(vm.GoString("print"))
+	if err != nil || !ok {
+		t.Fatalf("ContainsKey(print) = %v, %v, want true", ok, err)
+	}
+}
+
+func TestGlobalsClosed(t *testing.T) {
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		t.Fatalf("CreateLuaVm: %v", err)
+	}
+	luaVm.Close()
+
+	if luaVm.Globals() != nil {
+		t.Fatal("Globals of a closed VM is not nil")
+	}
+	if err := luaVm.SetGlobal("x", vm.NewValueInteger(1)); err == nil {
+		t.Fatal("SetGlobal on a closed VM succeeded")
+	}
+	if _, err := luaVm.GetGlobal("x"); err == nil {
+		t.Fatal("GetGlobal on a closed VM succeeded")
+	}
+}
@@ -0,0 +1,39 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// newVm creates a Lua VM with all safe standard libraries that is
+// closed once the test finishes
+func newVm(tb testing.TB) *vm.GoLuaVmWrapper {
+	tb.Helper()
+	luaVm, err := vm.CreateLuaVm()
+	if err != nil {
+		tb.Fatalf("CreateLuaVm: %v", err)
+	}
+	tb.Cleanup(luaVm.Close)
+	return luaVm
+}
+
+// exec runs code on luaVm, failing the test on error
+func exec(tb testing.TB, luaVm *vm.GoLuaVmWrapper, code string) []vm.Value {
+	tb.Helper()
+	rets, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: code})
+	if err != nil {
+		tb.Fatalf("ExecChunk: %v", err)
+	}
+	return rets
+}
+
+// decode decodes v into a T, failing the test on error
+func decode[T any](tb testing.TB, luaVm *vm.GoLuaVmWrapper, v vm.Value) T {
+	tb.Helper()
+	var out T
+	if err := luaVm.FromValue(v, &out); err != nil {
+		tb.Fatalf("FromValue: %v", err)
+	}
+	return out
+}
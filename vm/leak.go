@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HandleInfo describes a live (not yet closed) handle of a Lua VM
+// created with VmOptions.TrackHandles
+type HandleInfo struct {
+	// ID of the handle, increasing in creation order
+	ID uint64
+	// The type of the handle (e.g. "LuaTable")
+	Type string
+	// The Go stack trace of where the handle was created
+	Stack string
+}
+
+// handleTracker records the live handles of a Lua VM
+//
+// Handles are keyed by ID (and not by *object) so that the tracker does
+// not keep them alive: handles that are never closed are still finalized
+// (and untracked) once they are garbage collected.
+type handleTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	handles map[uint64]trackedHandle
+}
+
+type trackedHandle struct {
+	typ string
+	pcs []uintptr
+}
+
+func newHandleTracker() *handleTracker {
+	return &handleTracker{handles: make(map[uint64]trackedHandle)}
+}
+
+func (t *handleTracker) track(o *object) uint64 {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(4, pcs) // Skip runtime.Callers, track, trackHandle and newObject
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.handles[t.nextID] = trackedHandle{typ: o.tab.name, pcs: pcs[:n]}
+	return t.nextID
+}
+
+func (t *handleTracker) untrack(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.handles, id)
+}
+
+// trackHandle records o as a live handle if handle tracking is enabled
+func (s *vmState) trackHandle(o *object) {
+	if s == nil || s.handles == nil {
+		return
+	}
+	o.handleID = s.handles.track(o)
+}
+
+// untrackHandle removes o from the live handles if handle tracking is enabled
+func (s *vmState) untrackHandle(o *object) {
+	if s == nil || s.handles == nil || o.handleID == 0 {
+		return
+	}
+	s.handles.untrack(o.handleID)
+}
+
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// LiveHandles returns the handles of the Lua VM that have not been closed
+// (nor garbage collected) yet, in creation order.
+//
+// Returns nil if the Lua VM was not created with VmOptions.TrackHandles.
+func (l *GoLuaVmWrapper) LiveHandles() []HandleInfo {
+	t := l.state.handles
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	infos := make([]HandleInfo, 0, len(t.handles))
+	pcs := make([][]uintptr, 0, len(t.handles))
+	for id, h := range t.handles {
+		infos = append(infos, HandleInfo{ID: id, Type: h.typ})
+		pcs = append(pcs, h.pcs)
+	}
+	t.mu.Unlock()
+
+	// Symbolize outside of the lock as it is slow
+	for i := range infos {
+		infos[i].Stack = formatStack(pcs[i])
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// LiveHandleCounts returns the number of handles of the Lua VM that have
+// not been closed (nor garbage collected) yet by type.
+//
+// Returns nil if the Lua VM was not created with VmOptions.TrackHandles.
+func (l *GoLuaVmWrapper) LiveHandleCounts() map[string]int {
+	t := l.state.handles
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[string]int)
+	for _, h := range t.handles {
+		counts[h.typ]++
+	}
+	return counts
+}
+
+// LastHandleID returns the ID of the most recently created handle of the Lua VM.
+//
+// Handles with a greater ID than a previously returned LastHandleID were created
+// after that call. Returns 0 if the Lua VM was not created with VmOptions.TrackHandles.
+func (l *GoLuaVmWrapper) LastHandleID() uint64 {
+	t := l.state.handles
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.nextID
+}
@@ -0,0 +1,65 @@
+package vm_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gluau/gluau/vm"
+	"github.com/gluau/gluau/vm/vmtest"
+)
+
+func newTrackedVm(t *testing.T) *vm.GoLuaVmWrapper {
+	t.Helper()
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibAllSafe, TrackHandles: true})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+	t.Cleanup(luaVm.Close)
+	return luaVm
+}
+
+func TestLiveHandles(t *testing.T) {
+	luaVm := newTrackedVm(t)
+	vmtest.CheckLeaks(t, luaVm)
+
+	start := luaVm.LiveHandleCounts()["LuaTable"]
+	tab, err := luaVm.CreateTable()
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if got := luaVm.LiveHandleCounts()["LuaTable"]; got != start+1 {
+		t.Fatalf("live tables = %d, want %d", got, start+1)
+	}
+	handles := luaVm.LiveHandles()
+	if last := handles[len(handles)-1]; last.Type != "LuaTable" || last.ID != luaVm.LastHandleID() || last.Stack == "" {
+		t.Fatalf("last handle = %+v, want the new table", last)
+	}
+
+	tab.Close()
+	if got := luaVm.LiveHandleCounts()["LuaTable"]; got != start {
+		t.Fatalf("live tables after Close = %d, want %d", got, start)
+	}
+}
+
+func TestLiveHandlesFinalized(t *testing.T) {
+	luaVm := newTrackedVm(t)
+
+	start := luaVm.LiveHandleCounts()["LuaTable"]
+	func() {
+		// Never closed, only reachable from this function
+		if _, err := luaVm.CreateTable(); err != nil {
+			t.Fatalf("CreateTable: %v", err)
+		}
+	}()
+
+	// The tracker does not keep the handle alive, so it is finalized
+	deadline := time.Now().Add(5 * time.Second)
+	for luaVm.LiveHandleCounts()["LuaTable"] != start {
+		if time.Now().After(deadline) {
+			t.Fatal("unreachable handle was never finalized")
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
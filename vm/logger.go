@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LifecycleEventKind is the kind of a LifecycleEvent
+type LifecycleEventKind int
+
+const (
+	// The Lua VM was closed (all handles to it were dropped)
+	EventVmClosed LifecycleEventKind = iota
+	// A Go function created by CreateFunction was dropped by Luau
+	EventCallbackDropped
+	// The associated data of a userdata created by CreateUserData was dropped by Luau
+	EventUserDataDropped
+	// A handle (LuaTable, LuaString etc.) that was never closed was
+	// freed by the Go garbage collector
+	EventFinalizerFree
+)
+
+func (k LifecycleEventKind) String() string {
+	switch k {
+	case EventVmClosed:
+		return "vm closed"
+	case EventCallbackDropped:
+		return "callback dropped"
+	case EventUserDataDropped:
+		return "userdata data dropped"
+	case EventFinalizerFree:
+		return "finalizer free"
+	default:
+		return "unknown"
+	}
+}
+
+// LifecycleEvent is a lifecycle event of a Lua VM or one of its handles
+type LifecycleEvent struct {
+	Kind LifecycleEventKind
+	// The type of the handle the event is about (e.g. "LuaTable")
+	Handle string
+}
+
+// A Logger receives the lifecycle events of a Lua VM.
+//
+// Events may be sent from any goroutine (finalizers run on their own
+// goroutine), so LogEvent must be safe for concurrent use.
+type Logger interface {
+	LogEvent(event LifecycleEvent)
+}
+
+// LoggerFunc adapts a function to a Logger
+type LoggerFunc func(event LifecycleEvent)
+
+func (f LoggerFunc) LogEvent(event LifecycleEvent) {
+	f(event)
+}
+
+// slogLogger is a Logger that logs to a slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogLogger returns a Logger that logs lifecycle events to logger at the given level
+func NewSlogLogger(logger *slog.Logger, level slog.Level) Logger {
+	return &slogLogger{logger: logger, level: level}
+}
+
+func (s *slogLogger) LogEvent(event LifecycleEvent) {
+	if event.Handle == "" {
+		s.logger.Log(context.Background(), s.level, event.Kind.String())
+		return
+	}
+	s.logger.Log(context.Background(), s.level, event.Kind.String(), "handle", event.Handle)
+}
+
+// logEvent sends a lifecycle event to the Logger of the Lua VM (if any)
+func (s *vmState) logEvent(kind LifecycleEventKind, handle string) {
+	if s == nil || s.opts.Logger == nil {
+		return
+	}
+	s.opts.Logger.LogEvent(LifecycleEvent{Kind: kind, Handle: handle})
+}
@@ -0,0 +1,56 @@
+package vm_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// eventLog is a Logger recording the events it receives
+type eventLog struct {
+	mu     sync.Mutex
+	events []vm.LifecycleEvent
+}
+
+func (l *eventLog) LogEvent(event vm.LifecycleEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) has(kind vm.LifecycleEventKind) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoggerEvents(t *testing.T) {
+	log := &eventLog{}
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibAllSafe, Logger: log})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+
+	fn, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) { return nil, nil })
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	fn.Close()
+	if err := luaVm.GCCollect(); err != nil {
+		t.Fatalf("GCCollect: %v", err)
+	}
+	if !log.has(vm.EventCallbackDropped) {
+		t.Error("no callback dropped event after the function was collected")
+	}
+
+	luaVm.Close()
+	if !log.has(vm.EventVmClosed) {
+		t.Error("no vm closed event after Close")
+	}
+}
@@ -0,0 +1,425 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ToValue converts a Go value into a Luau value.
+//
+// The conversion rules are as follows:
+//
+//   - nil, nil pointers, nil maps and nil slices become nil
+//   - bools become booleans
+//   - signed and unsigned integers become integers (unsigned integers
+//     larger than math.MaxInt64 are an error)
+//   - floats become numbers
+//   - strings and []byte become strings
+//   - slices and arrays become array-like tables (starting at index 1)
+//   - maps become tables, with their keys converted using the same rules
+//   - structs become tables keyed by field name (see below)
+//   - pointers and interfaces are converted by converting the value they point to
+//   - Values (and LuaTable, LuaFunction etc.) are returned as-is
+//
+// Types implementing LuaMarshaler (and types with a converter registered
+// using RegisterConverter) are converted using their custom conversion.
+//
+// Struct fields can be customized using the `lua` struct tag, in the same
+// manner as `json` tags in encoding/json:
+//
+//	Name    string `lua:"name"`           // stored under "name"
+//	Port    int    `lua:"port,omitempty"` // omitted if zero
+//	Secret  string `lua:"-"`              // never stored
+//
+// Unexported fields are ignored and the fields of embedded structs without a
+// name tag are stored as if they were fields of the outer struct.
+//
+// Cyclic input (such as a struct containing a pointer to itself) results in
+// an error instead of infinite recursion.
+func (l *GoLuaVmWrapper) ToValue(v any) (Value, error) {
+	e := &encoder{lua: l, visiting: map[visitKey]struct{}{}}
+	val, _, err := e.encode(reflect.ValueOf(v), "")
+	return val, err
+}
+
+// luaTag is a parsed `lua` struct tag
+type luaTag struct {
+	name      string
+	skip      bool
+	omitEmpty bool
+}
+
+func parseLuaTag(tag string) luaTag {
+	if tag == "-" {
+		return luaTag{skip: true}
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	t := luaTag{name: name}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == "omitempty" {
+			t.omitEmpty = true
+		}
+	}
+	return t
+}
+
+// structField describes a struct field that is converted to/from a table field.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields returns the fields of struct type t that are converted
+// to/from table fields, flattening embedded structs.
+//
+// Fields of the outer struct take precedence over fields of embedded
+// structs with the same name.
+func structFields(t reflect.Type) []structField {
+	return embeddedFields(t, map[reflect.Type]struct{}{})
+}
+
+// embeddedFields implements structFields. visited holds the struct types
+// being flattened, embedded structs of those types are skipped (as
+// encoding/json does) so that self-embedding types don't recurse forever.
+func embeddedFields(t reflect.Type, visited map[reflect.Type]struct{}) []structField {
+	visited[t] = struct{}{}
+	defer delete(visited, t)
+
+	var fields []structField
+	seen := map[string]struct{}{}
+
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := parseLuaTag(f.Tag.Get("lua"))
+		if tag.skip {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && tag.name == "" && ft.Kind() == reflect.Struct {
+			// Embedded structs are flattened after the direct fields
+			// so that the direct fields take precedence
+			embedded = append(embedded, f)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := tag.name
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		fields = append(fields, structField{name: name, index: f.Index, omitEmpty: tag.omitEmpty})
+	}
+
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if _, ok := visited[ft]; ok {
+			continue
+		}
+		for _, sf := range embeddedFields(ft, visited) {
+			if _, ok := seen[sf.name]; ok {
+				continue
+			}
+			seen[sf.name] = struct{}{}
+			sf.index = append([]int{f.Index[0]}, sf.index...)
+			fields = append(fields, sf)
+		}
+	}
+
+	return fields
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but returns
+// false instead of panicking when a nil embedded pointer is encountered.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// fieldPath returns the path of a field within the value at path
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// indexPath returns the path of an element within the value at path
+func indexPath(path string, idx int) string {
+	return path + "[" + strconv.Itoa(idx) + "]"
+}
+
+// pathError prefixes err with path (if any)
+func pathError(path string, format string, args ...any) error {
+	if path == "" {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf("%s: "+format, append([]any{path}, args...)...)
+}
+
+var valueType = reflect.TypeOf((*Value)(nil)).Elem()
+
+// visitKey identifies a pointer, map or slice currently being
+// converted for cycle detection
+type visitKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+type encoder struct {
+	lua      *GoLuaVmWrapper
+	visiting map[visitKey]struct{}
+}
+
+// enter marks v as being converted, returning an error if it already is
+func (e *encoder) enter(v reflect.Value, path string) (visitKey, error) {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		// Subslices share the same pointer
+		key.len = v.Len()
+	}
+	if _, ok := e.visiting[key]; ok {
+		return key, pathError(path, "cyclic value of type %s", v.Type())
+	}
+	e.visiting[key] = struct{}{}
+	return key, nil
+}
+
+// encode converts v to a Value. owned is true if the returned
+// value was created by the encoder (and not passed in by the caller).
+func (e *encoder) encode(v reflect.Value, path string) (val Value, owned bool, err error) {
+	if !v.IsValid() {
+		return &ValueNil{}, false, nil
+	}
+
+	if v.CanInterface() {
+		if val, ok := luaValueOf(v); ok {
+			return val, false, nil
+		}
+	}
+
+	if val, handled, owned, err := e.lua.marshalCustom(v, path); handled {
+		return val, owned, err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NewValueBoolean(v.Bool()), false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewValueInteger(v.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, false, pathError(path, "integer %d overflows a Luau integer", u)
+		}
+		return NewValueInteger(int64(u)), false, nil
+	case reflect.Float32, reflect.Float64:
+		return NewValueNumber(v.Float()), false, nil
+	case reflect.String:
+		return GoString(v.String()), false, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return &ValueNil{}, false, nil
+		}
+		return e.encode(v.Elem(), path)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return &ValueNil{}, false, nil
+		}
+		key, err := e.enter(v, path)
+		if err != nil {
+			return nil, false, err
+		}
+		defer delete(e.visiting, key)
+		return e.encode(v.Elem(), path)
+	case reflect.Slice:
+		if v.IsNil() {
+			return &ValueNil{}, false, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return GoString(v.Bytes()), false, nil
+		}
+		key, err := e.enter(v, path)
+		if err != nil {
+			return nil, false, err
+		}
+		defer delete(e.visiting, key)
+		return e.encodeArray(v, path)
+	case reflect.Array:
+		return e.encodeArray(v, path)
+	case reflect.Map:
+		if v.IsNil() {
+			return &ValueNil{}, false, nil
+		}
+		key, err := e.enter(v, path)
+		if err != nil {
+			return nil, false, err
+		}
+		defer delete(e.visiting, key)
+		return e.encodeMap(v, path)
+	case reflect.Struct:
+		return e.encodeStruct(v, path)
+	default:
+		return nil, false, pathError(path, "cannot convert Go value of type %s to a Luau value", v.Type())
+	}
+}
+
+// luaValueOf returns v as a Value if it already is a
+// Value or Lua object (such as a *LuaTable)
+func luaValueOf(v reflect.Value) (Value, bool) {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if v.Type().Implements(valueType) {
+				return &ValueNil{}, true
+			}
+			return nil, false
+		}
+	}
+
+	switch obj := v.Interface().(type) {
+	case Value:
+		return obj, true
+	case *LuaString:
+		return obj.ToValue(), true
+	case *LuaTable:
+		return obj.ToValue(), true
+	case *LuaFunction:
+		return obj.ToValue(), true
+	case *LuaUserData:
+		return obj.ToValue(), true
+	case *LuaThread:
+		return obj.ToValue(), true
+	case *LuaBuffer:
+		return obj.ToValue(), true
+	}
+	return nil, false
+}
+
+// setField converts v and sets it as key in tab, closing
+// the converted value afterwards if it was created by the encoder
+func (e *encoder) setField(tab *LuaTable, key Value, v reflect.Value, path string) error {
+	val, owned, err := e.encode(v, path)
+	if err != nil {
+		return err
+	}
+	err = tab.RawSet(key, val)
+	if owned {
+		// The table now references the value so our handle
+		// to it is no longer needed
+		val.Close()
+	}
+	if err != nil {
+		return pathError(path, "%w", err)
+	}
+	return nil
+}
+
+func (e *encoder) encodeArray(v reflect.Value, path string) (Value, bool, error) {
+	n := v.Len()
+	tab, err := e.lua.CreateTableWithCapacity(n, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for i := 0; i < n; i++ {
+		if err := e.setField(tab, NewValueInteger(int64(i+1)), v.Index(i), indexPath(path, i)); err != nil {
+			tab.Close()
+			return nil, false, err
+		}
+	}
+	return tab.ToValue(), true, nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value, path string) (Value, bool, error) {
+	tab, err := e.lua.CreateTableWithCapacity(0, v.Len())
+	if err != nil {
+		return nil, false, err
+	}
+
+	iter := v.MapRange()
+	for iter.Next() {
+		elemPath := path + "[" + fmt.Sprint(iter.Key()) + "]"
+		key, keyOwned, err := e.encode(iter.Key(), elemPath)
+		if err != nil {
+			tab.Close()
+			return nil, false, err
+		}
+		if key.Type() == LuaValueNil {
+			tab.Close()
+			return nil, false, pathError(elemPath, "map key cannot be nil")
+		}
+
+		err = e.setField(tab, key, iter.Value(), elemPath)
+		if keyOwned {
+			key.Close()
+		}
+		if err != nil {
+			tab.Close()
+			return nil, false, err
+		}
+	}
+	return tab.ToValue(), true, nil
+}
+
+func (e *encoder) encodeStruct(v reflect.Value, path string) (Value, bool, error) {
+	fields := structFields(v.Type())
+	tab, err := e.lua.CreateTableWithCapacity(0, len(fields))
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+
+		if err := e.setField(tab, GoString(f.name), fv, fieldPath(path, f.name)); err != nil {
+			tab.Close()
+			return nil, false, err
+		}
+	}
+	return tab.ToValue(), true, nil
+}
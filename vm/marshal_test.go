@@ -0,0 +1,132 @@
+package vm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// checkInLua sets the global v to val and runs the assertions in code
+func checkInLua(t *testing.T, luaVm *vm.GoLuaVmWrapper, val vm.Value, code string) {
+	t.Helper()
+	if err := luaVm.SetGlobal("v", val); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	exec(t, luaVm, code)
+}
+
+func TestToValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	type Server struct {
+		Host   string `lua:"host"`
+		Port   uint16 `lua:"port,omitempty"`
+		Secret string `lua:"-"`
+		hidden int
+	}
+	type Config struct {
+		Name    string
+		Servers []Server   `lua:"servers"`
+		Limits  [2]float64 `lua:"limits"`
+		Tags    map[string]bool
+		Raw     []byte
+		Next    *Config
+	}
+	cfg := Config{
+		Name:    "prod",
+		Servers: []Server{{Host: "a", Port: 80, Secret: "x"}, {Host: "b"}},
+		Limits:  [2]float64{0.5, 2},
+		Tags:    map[string]bool{"primary": true},
+		Raw:     []byte("bytes"),
+	}
+	val, err := luaVm.ToValue(cfg)
+	if err != nil {
+		t.Fatalf("ToValue: %v", err)
+	}
+	defer val.Close()
+	checkInLua(t, luaVm, val, `
+		assert(v.Name == "prod")
+		assert(#v.servers == 2)
+		assert(v.servers[1].host == "a" and v.servers[1].port == 80)
+		assert(v.servers[2].port == nil, "omitempty")
+		assert(v.servers[1].Secret == nil and v.servers[1].hidden == nil)
+		assert(v.limits[1] == 0.5 and v.limits[2] == 2)
+		assert(v.Tags.primary == true)
+		assert(v.Raw == "bytes")
+		assert(v.Next == nil)
+	`)
+}
+
+func TestToValuePrimitives(t *testing.T) {
+	luaVm := newVm(t)
+
+	n := 5
+	for _, tc := range []struct {
+		in   any
+		want string
+	}{
+		{nil, "nil"},
+		{(*int)(nil), "nil"},
+		{true, "boolean"},
+		{int8(-3), "number"},
+		{uint32(3), "number"},
+		{1.5, "number"},
+		{"s", "string"},
+		{&n, "number"},
+	} {
+		val, err := luaVm.ToValue(tc.in)
+		if err != nil {
+			t.Fatalf("ToValue(%#v): %v", tc.in, err)
+		}
+		if err := luaVm.SetGlobal("v", val); err != nil {
+			t.Fatalf("SetGlobal: %v", err)
+		}
+		val.Close()
+		rets := exec(t, luaVm, `return typeof(v)`)
+		if got := decode[string](t, luaVm, rets[0]); got != tc.want {
+			t.Errorf("typeof(ToValue(%#v)) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := luaVm.ToValue(uint64(1 << 63)); err == nil {
+		t.Error("ToValue of an overflowing uint64 succeeded")
+	}
+}
+
+func TestToValueCycle(t *testing.T) {
+	luaVm := newVm(t)
+
+	type node struct {
+		Next *node
+	}
+	n := &node{}
+	n.Next = n
+	if _, err := luaVm.ToValue(n); err == nil || !strings.Contains(err.Error(), "cycl") {
+		t.Fatalf("ToValue of a cycle = %v, want a cycle error", err)
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	if _, err := luaVm.ToValue(m); err == nil {
+		t.Fatal("ToValue of a cyclic map succeeded")
+	}
+}
+
+func TestToValueSelfEmbedding(t *testing.T) {
+	luaVm := newVm(t)
+
+	type Node struct {
+		*Node
+		V int
+	}
+	val, err := luaVm.ToValue(Node{Node: &Node{V: 2}, V: 1})
+	if err != nil {
+		t.Fatalf("ToValue: %v", err)
+	}
+	defer val.Close()
+	checkInLua(t, luaVm, val, `
+		assert(v.V == 1)
+		assert(v.Node == nil)
+	`)
+}
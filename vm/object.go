@@ -1,13 +1,14 @@
 package vm
 
 import (
-	"errors"
 	"runtime"
 	"sync"
 )
 import "C"
 
 type objectTab struct {
+	// name is the name of the handle type (for lifecycle events)
+	name string
 	// dtor is the destructor function for the object
 	// called on Close() or when finalizer is called
 	dtor func(ptr *C.void)
@@ -18,19 +19,28 @@ type object struct {
 	sync.RWMutex // read = anything that doesnt close the object, write = Close()
 	ptr          *C.void
 	tab          objectTab
+	state        *vmState // state of the Lua VM owning the object (may be nil)
+	handleID     uint64   // ID of the object in the handle tracker (0 if untracked)
 }
 
 // NewObject creates a Object from a C pointer.
-func newObject(ptr *C.void, tab objectTab) *object {
+func newObject(ptr *C.void, tab objectTab, state *vmState) *object {
 	if ptr == nil {
 		return nil // Return nil if the pointer is nil
 	}
 
-	obj := &object{ptr: ptr, tab: tab}
-	runtime.SetFinalizer(obj, (*object).Close) // Set finalizer to clean up LuaString
+	obj := &object{ptr: ptr, tab: tab, state: state}
+	runtime.SetFinalizer(obj, (*object).finalize) // Set finalizer to clean up LuaString
+	state.trackHandle(obj)
 	return obj
 }
 
+// finalize is called by the Go garbage collector on objects that were never closed
+func (o *object) finalize() {
+	o.state.logEvent(EventFinalizerFree, o.tab.name)
+	o.Close()
+}
+
 // PointerLock returns the C pointer of the object after
 // acquiring a read lock. Use this when you need to ensure
 func (o *object) PointerLock() (*C.void, error) {
@@ -39,7 +49,7 @@ func (o *object) PointerLock() (*C.void, error) {
 	defer o.RWMutex.RUnlock()
 
 	if o.ptr == nil {
-		return nil, errors.New("cannot use closed object")
+		return nil, errClosedObject()
 	}
 
 	return o.ptr, nil
@@ -49,12 +59,30 @@ func (o *object) PointerLock() (*C.void, error) {
 // without acquiring the read lock. Use with caution.
 func (o *object) PointerNoLock() (*C.void, error) {
 	if o.ptr == nil {
-		return nil, errors.New("cannot use closed object")
+		return nil, errClosedObject()
 	}
 
 	return o.ptr, nil
 }
 
+// TakePointer returns the C pointer of the object, transferring ownership
+// of it to the caller (usually Rust) and marking the object as closed
+// without calling the destructor.
+func (o *object) TakePointer() (*C.void, error) {
+	o.RWMutex.Lock()
+	defer o.RWMutex.Unlock()
+
+	if o.ptr == nil {
+		return nil, errClosedObject()
+	}
+
+	ptr := o.ptr
+	o.ptr = nil                  // Ownership was transferred
+	runtime.SetFinalizer(o, nil) // Remove finalizer as there is nothing to free
+	o.state.untrackHandle(o)
+	return ptr, nil
+}
+
 // Close cleans up the Object by calling the destructor and setting the pointer to nil.
 func (o *object) Close() {
 	// Safety: Close() can only be called if no one is reading/using the object.
@@ -70,4 +98,5 @@ func (o *object) Close() {
 	}
 	o.ptr = nil                  // Prevent double free
 	runtime.SetFinalizer(o, nil) // Remove finalizer to prevent double calls
+	o.state.untrackHandle(o)
 }
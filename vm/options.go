@@ -0,0 +1,72 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+
+// StdLib is a set of Luau standard libraries to load into a Lua VM.
+//
+// The base library (print, pcall, etc.) is always loaded.
+type StdLib uint32
+
+const (
+	StdLibCoroutine StdLib = 1 << iota // coroutine library
+	StdLibTable                        // table library
+	StdLibOs                           // os library
+	StdLibString                       // string library
+	StdLibUtf8                         // utf8 library
+	StdLibBit32                        // bit32 library
+	StdLibMath                         // math library
+	StdLibBuffer                       // buffer library (Luau-specific)
+	StdLibVector                       // vector library (Luau-specific)
+	StdLibDebug                        // debug library
+
+	// StdLibNone loads no standard library besides the base library
+	StdLibNone StdLib = 0
+	// StdLibAllSafe loads all of the Luau standard libraries except debug.
+	//
+	// This is what CreateLuaVm uses.
+	StdLibAllSafe = StdLibCoroutine | StdLibTable | StdLibOs | StdLibString | StdLibUtf8 | StdLibBit32 | StdLibMath | StdLibBuffer | StdLibVector
+	// StdLibAll loads all of the Luau standard libraries including debug.
+	StdLibAll = StdLibAllSafe | StdLibDebug
+)
+
+// Has returns true if all libraries in other are contained in s
+func (s StdLib) Has(other StdLib) bool {
+	return s&other == other
+}
+
+// VmOptions represents the options for creating a Lua VM.
+type VmOptions struct {
+	// The standard libraries to load into the VM.
+	//
+	// Note that the zero value loads no standard library (besides
+	// the base library). Use StdLibAllSafe to load all of them.
+	StdLibs StdLib
+	// The Logger to send lifecycle events (VM closed, callbacks dropped etc.) to.
+	//
+	// If nil, lifecycle events are discarded.
+	Logger Logger
+	// If true, the Go stack trace of where each handle (LuaTable, LuaString etc.)
+	// is created is recorded until the handle is closed, to find leaked handles
+	// with LiveHandles.
+	//
+	// This is a debugging aid and makes creating handles much slower.
+	TrackHandles bool
+	// How panics in Go callbacks (see CreateFunction) are handled.
+	//
+	// The zero value (PanicAsError) raises them as Luau errors.
+	PanicPolicy PanicPolicy
+	// Called with the recovered panic of a Go callback if PanicPolicy
+	// is PanicCallHook. The returned error (if any) is raised as a Luau
+	// error in place of the panic; if nil, the callback returns no values.
+	PanicHook func(p *CallbackPanic) error
+}
+
+// Converts VmOptions to C struct
+func (opts *VmOptions) toC() C.struct_LuaVmOptions {
+	return C.struct_LuaVmOptions{
+		stdlib: C.uint32_t(opts.StdLibs),
+	}
+}
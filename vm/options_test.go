@@ -0,0 +1,29 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestStdLibs(t *testing.T) {
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibString})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+	defer luaVm.Close()
+
+	if got := luaVm.StdLibs(); got != vm.StdLibString || !got.Has(vm.StdLibString) || got.Has(vm.StdLibMath) {
+		t.Fatalf("StdLibs = %b, want string only", got)
+	}
+	rets := exec(t, luaVm, `return string ~= nil, math == nil, print ~= nil`)
+	for i, ret := range rets {
+		if !decode[bool](t, luaVm, ret) {
+			t.Errorf("check %d failed: only the string and base libraries should be loaded", i+1)
+		}
+	}
+
+	if got := newVm(t).StdLibs(); got != vm.StdLibAllSafe {
+		t.Fatalf("CreateLuaVm StdLibs = %b, want StdLibAllSafe", got)
+	}
+}
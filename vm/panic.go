@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy determines how panics in Go callbacks are handled.
+//
+// Panics can never unwind through the Luau (and Rust) frames between
+// a Go callback and its caller, so they are always recovered when the
+// callback returns to Luau first.
+type PanicPolicy int
+
+const (
+	// PanicAsError raises the panic as a Luau error. The error returned by
+	// the call into Luau wraps the *CallbackPanic (use errors.As to access it).
+	PanicAsError PanicPolicy = iota
+	// PanicRepanic unwinds out of Luau (as with PanicAsError) and then
+	// panics again with the *CallbackPanic on the goroutine that called into
+	// Luau, once the call (LuaFunction.Call etc., LuaThread.Resume or a
+	// LuaTable method or ToString calling a metamethod) returns.
+	//
+	// The panic is re-raised even if Luau code catches the error using pcall.
+	PanicRepanic
+	// PanicCallHook calls VmOptions.PanicHook with the *CallbackPanic,
+	// raising the error it returns (if any). Falls back to PanicAsError
+	// if PanicHook is nil.
+	PanicCallHook
+)
+
+// CallbackPanic is a panic recovered from a Go callback
+type CallbackPanic struct {
+	// The value passed to panic
+	Value any
+	// Go stack trace of the panic, as returned by debug.Stack
+	Stack []byte
+
+	// Program counters of the stack of the panic (see LuaError.StackTrace)
+	pcs []uintptr
+}
+
+func (p *CallbackPanic) Error() string {
+	return fmt.Sprintf("panic in CreateFunction callback: %v", p.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (p *CallbackPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// newCallbackPanic returns the CallbackPanic for the recovered value r.
+// It must be called from the deferred function recovering r
+func newCallbackPanic(r any) *CallbackPanic {
+	if p, ok := r.(*CallbackPanic); ok {
+		return p // Re-panicked by a nested call, see PanicRepanic
+	}
+	return &CallbackPanic{Value: r, Stack: debug.Stack(), pcs: callers(1)}
+}
+
+// handleCallbackPanic handles the panic of a Go callback according to
+// the panic policy, returning the error to raise in Luau (if any)
+func (s *vmState) handleCallbackPanic(p *CallbackPanic) error {
+	switch s.opts.PanicPolicy {
+	case PanicRepanic:
+		s.pendingPanic.CompareAndSwap(nil, p)
+	case PanicCallHook:
+		if s.opts.PanicHook != nil {
+			return s.callPanicHook(p)
+		}
+	}
+	return p
+}
+
+// callPanicHook calls the PanicHook, recovering any panic in it as
+// the hook is itself called before returning to Luau
+func (s *vmState) callPanicHook(p *CallbackPanic) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in PanicHook: %v", r)
+		}
+	}()
+	return s.opts.PanicHook(p)
+}
+
+// repanic panics with the pending panic of a Go callback (if any), see
+// PanicRepanic. It is deferred by every method that can call into Luau
+// code (and hence Go callbacks), including through metamethods
+func (s *vmState) repanic() {
+	if s == nil {
+		return
+	}
+	if p := s.pendingPanic.Swap(nil); p != nil {
+		panic(p)
+	}
+}
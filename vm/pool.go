@@ -0,0 +1,324 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned by VmPool.Get once the pool is closed
+var ErrPoolClosed = errors.New("vm pool is closed")
+
+// VmPoolOptions represents the options for creating a VmPool.
+type VmPoolOptions struct {
+	// The number of Lua VMs to keep in the pool.
+	Size int
+	// The options to create each Lua VM with.
+	//
+	// If VmOptions.StdLibs is StdLibNone, the Lua VMs are created with
+	// StdLibAllSafe (as with CreateLuaVm).
+	VmOptions VmOptions
+	// Init is called once for every new Lua VM before it is added to the pool.
+	//
+	// Use this to install host libraries (globals, functions etc.). Once Init
+	// returns, the globals and the tables in them are made readonly (see VmPool).
+	Init func(vm *GoLuaVmWrapper) error
+	// Reset is called every time a Lua VM is put back into the pool.
+	//
+	// Returning an error discards the Lua VM (a new one is created in its place)
+	Reset func(vm *GoLuaVmWrapper) error
+	// If set, a Lua VM using more than MaxMemory bytes after being
+	// garbage collected on Put is discarded instead of being reused.
+	MaxMemory int
+}
+
+// A VmPool is a pool of pre-initialized Lua VMs.
+//
+// Each Lua VM is only handed out to one user at a time, and is given a
+// fresh environment (see PooledVm.Env) on every Get. To keep scripts from
+// leaking state from one use to the next through the shared globals, the
+// globals, the tables directly in them (the standard libraries and tables
+// installed by Init, e.g. string) and the string metatable are made readonly
+// once a Lua VM is initialized, like Luau's own sandbox mode. Assigning to
+// them (string.foo = ...) raises an error instead.
+//
+// This is not a complete sandbox: state reachable in other ways (such as
+// tables nested deeper in the globals, upvalues of host functions or
+// registry values) is still shared between uses. Use Reset (or Discard)
+// to clean up such state.
+type VmPool struct {
+	opts VmPoolOptions
+	vms  chan *pooledVmEntry
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+	errv   error // last error from creating a replacement Lua VM
+}
+
+type pooledVmEntry struct {
+	vm *GoLuaVmWrapper
+	// metatable of the environments, with __index set to the globals
+	envMt *LuaTable
+}
+
+// A PooledVm is a Lua VM handed out by a VmPool.
+//
+// It must be given back using VmPool.Put once done.
+type PooledVm struct {
+	*GoLuaVmWrapper
+
+	// Env is a fresh environment table for this use of the Lua VM.
+	//
+	// Reads of missing keys fall back to the (readonly) globals of the Lua VM
+	// (so the standard library and host APIs set up by Init are available),
+	// while writes stay in Env. Pass this as ChunkOpts.Env when loading chunks.
+	Env *LuaTable
+
+	entry   *pooledVmEntry
+	discard bool
+}
+
+// Discard marks the Lua VM as unusable (e.g. after it errored or exceeded
+// its limits) so that VmPool.Put closes it instead of putting it back into
+// the pool.
+func (p *PooledVm) Discard() {
+	p.discard = true
+}
+
+// NewVmPool creates a new VmPool, creating and initializing
+// opts.Size Lua VMs up front.
+func NewVmPool(opts VmPoolOptions) (*VmPool, error) {
+	if opts.Size <= 0 {
+		return nil, errors.New("vm pool size must be greater than 0")
+	}
+
+	if opts.VmOptions.StdLibs == StdLibNone {
+		opts.VmOptions.StdLibs = StdLibAllSafe
+	}
+
+	pool := &VmPool{
+		opts: opts,
+		vms:  make(chan *pooledVmEntry, opts.Size),
+	}
+	for i := 0; i < opts.Size; i++ {
+		entry, err := pool.newEntry()
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		pool.vms <- entry
+	}
+	return pool, nil
+}
+
+func (p *VmPool) newEntry() (*pooledVmEntry, error) {
+	vm, err := CreateLuaVmWithOptions(p.opts.VmOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.opts.Init != nil {
+		if err := p.opts.Init(vm); err != nil {
+			vm.Close()
+			return nil, err
+		}
+	}
+
+	if err := sandboxGlobals(vm); err != nil {
+		vm.Close()
+		return nil, err
+	}
+
+	envMt, err := vm.CreateTable()
+	if err != nil {
+		vm.Close()
+		return nil, err
+	}
+	globals := vm.Globals()
+	if globals == nil {
+		envMt.Close()
+		vm.Close()
+		return nil, errors.New("cannot use closed object")
+	}
+	defer globals.Close()
+	if err := envMt.Set(GoString("__index"), globals.ToValue()); err != nil {
+		envMt.Close()
+		vm.Close()
+		return nil, err
+	}
+	envMt.SetReadonly(true) // Shared by all environments
+
+	return &pooledVmEntry{vm: vm, envMt: envMt}, nil
+}
+
+// sandboxGlobals makes the globals of vm, the tables directly in them
+// and the string metatable readonly (see VmPool)
+func sandboxGlobals(vm *GoLuaVmWrapper) error {
+	globals := vm.Globals()
+	if globals == nil {
+		return errors.New("cannot use closed object")
+	}
+	defer globals.Close()
+
+	err := globals.ForEach(func(key, value Value) error {
+		defer key.Close()
+		defer value.Close()
+		if t, ok := value.(*ValueTable); ok {
+			t.Value().SetReadonly(true)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	globals.SetReadonly(true)
+
+	rets, err := vm.ExecChunk(ChunkOpts{Name: "sandbox", Code: "return getmetatable('')"})
+	if err != nil {
+		return err
+	}
+	for _, ret := range rets {
+		if t, ok := ret.(*ValueTable); ok {
+			t.Value().SetReadonly(true)
+		}
+		ret.Close()
+	}
+	return nil
+}
+
+func (e *pooledVmEntry) close() {
+	e.envMt.Close()
+	e.vm.Close()
+}
+
+// Get takes a Lua VM out of the pool, waiting until one is available
+// or ctx is done.
+func (p *VmPool) Get(ctx context.Context) (*PooledVm, error) {
+	for {
+		select {
+		case entry, ok := <-p.vms:
+			if !ok {
+				return nil, ErrPoolClosed
+			}
+
+			env, err := entry.vm.CreateTable()
+			if err == nil {
+				err = env.SetMetatable(entry.envMt)
+			}
+			if err != nil {
+				// The Lua VM is unusable, replace it and try again
+				env.Close()
+				p.replace(entry)
+				continue
+			}
+
+			return &PooledVm{GoLuaVmWrapper: entry.vm, Env: env, entry: entry}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Put gives a Lua VM back to the pool.
+//
+// The Lua VM is garbage collected and checked against the limits of the pool
+// before being reused. Lua VMs that were discarded, fail these checks or fail
+// the Reset function are closed and replaced by a new Lua VM.
+func (p *VmPool) Put(vm *PooledVm) {
+	if vm == nil || vm.entry == nil {
+		return
+	}
+	entry := vm.entry
+	vm.entry = nil // Prevent double Put
+
+	vm.Env.Close()
+	vm.Env = nil
+
+	if vm.discard || !p.reset(entry.vm) {
+		p.replace(entry)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		entry.close()
+		return
+	}
+	p.vms <- entry
+}
+
+// reset prepares a Lua VM for reuse, returning false if it should be discarded
+func (p *VmPool) reset(vm *GoLuaVmWrapper) bool {
+	vm.SetAppData(nil) // App data is request-scoped
+	if err := vm.GCCollect(); err != nil {
+		return false
+	}
+	if p.opts.MaxMemory > 0 && vm.UsedMemory() > p.opts.MaxMemory {
+		return false
+	}
+	if p.opts.Reset != nil {
+		if err := p.opts.Reset(vm); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// replace closes a Lua VM and creates a new one in its place in the background
+func (p *VmPool) replace(entry *pooledVmEntry) {
+	entry.close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		newEntry, err := p.newEntry()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err != nil {
+			// The pool shrinks by one, keep the error around for Err()
+			p.errv = err
+			return
+		}
+		if p.closed {
+			newEntry.close()
+			return
+		}
+		p.vms <- newEntry
+	}()
+}
+
+// Err returns the last error that occurred while creating a replacement
+// Lua VM (in which case the pool has fewer Lua VMs than its size), if any.
+func (p *VmPool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.errv
+}
+
+// Close closes the pool and all Lua VMs currently in it.
+//
+// Lua VMs that are currently handed out are closed once they are Put back.
+func (p *VmPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	p.wg.Wait() // Wait for pending replacements
+	close(p.vms)
+	for entry := range p.vms {
+		entry.close()
+	}
+}
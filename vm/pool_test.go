@@ -0,0 +1,111 @@
+package vm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func newPool(t *testing.T, opts vm.VmPoolOptions) *vm.VmPool {
+	t.Helper()
+	pool, err := vm.NewVmPool(opts)
+	if err != nil {
+		t.Fatalf("NewVmPool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// runPooled gets a Lua VM from pool, runs code in its Env and puts it back
+func runPooled(t *testing.T, pool *vm.VmPool, code string) ([]vm.Value, error) {
+	t.Helper()
+	pvm, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer pool.Put(pvm)
+	return pvm.ExecChunk(vm.ChunkOpts{Name: "test", Code: code, Env: pvm.Env})
+}
+
+func TestPoolIsolation(t *testing.T) {
+	pool := newPool(t, vm.VmPoolOptions{Size: 1})
+
+	if _, err := runPooled(t, pool, `foo = 1`); err != nil {
+		t.Fatalf("setting a global: %v", err)
+	}
+	// The standard library is loaded by default and the
+	// global set by the previous use is not visible
+	if _, err := runPooled(t, pool, `
+		assert(foo == nil, "global set by the previous use is visible")
+		assert(string.upper("a") == "A")
+	`); err != nil {
+		t.Fatalf("reading globals: %v", err)
+	}
+
+	// Shared tables cannot be modified
+	for _, code := range []string{
+		`string.foo = 1`,
+		`rawset(string, "foo", 1)`,
+		`string.upper = nil`,
+		`getmetatable("").__index = {}`,
+		`getfenv(0).foo = 1`,
+		`getmetatable(getfenv()).__index = {}`,
+	} {
+		_, err := runPooled(t, pool, code)
+		if err == nil || !strings.Contains(err.Error(), "readonly") {
+			t.Errorf("%s = %v, want a readonly table error", code, err)
+		}
+	}
+	if _, err := runPooled(t, pool, `
+		assert(string.foo == nil, "string.foo set by a previous use is visible")
+		assert(string.upper("b") == "B")
+	`); err != nil {
+		t.Fatalf("reading string: %v", err)
+	}
+}
+
+func TestPoolInit(t *testing.T) {
+	pool := newPool(t, vm.VmPoolOptions{
+		Size: 2,
+		Init: func(v *vm.GoLuaVmWrapper) error {
+			_, err := v.ExecChunk(vm.ChunkOpts{Name: "init", Code: `host = { version = 3 }`})
+			return err
+		},
+	})
+
+	rets, err := runPooled(t, pool, `return host.version`)
+	if err != nil {
+		t.Fatalf("ExecChunk: %v", err)
+	}
+	if v, ok := rets[0].(*vm.ValueInteger); !ok || v.Value() != 3 {
+		if n, ok := rets[0].(*vm.ValueNumber); !ok || n.Value() != 3 {
+			t.Fatalf("host.version = %#v, want 3", rets[0])
+		}
+	}
+	if _, err := runPooled(t, pool, `host.version = 4`); err == nil {
+		t.Fatal("modifying a host table succeeded")
+	}
+}
+
+func TestPoolDiscard(t *testing.T) {
+	pool := newPool(t, vm.VmPoolOptions{Size: 1})
+
+	pvm, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	first := pvm.GoLuaVmWrapper
+	pvm.Discard()
+	pool.Put(pvm)
+
+	pvm, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get after Discard: %v", err)
+	}
+	defer pool.Put(pvm)
+	if pvm.GoLuaVmWrapper == first {
+		t.Fatal("discarded Lua VM was reused")
+	}
+}
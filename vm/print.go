@@ -0,0 +1,135 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"unsafe"
+)
+
+// Name of the registry slot the original print function is saved in
+const originalPrintRegistryName = "gluau.print"
+
+// PrintHandler is called whenever a script calls print.
+//
+// vm is only open until the handler returns.
+type PrintHandler = func(vm *GoLuaVmWrapper, args []Value)
+
+// ToString converts a value to a string the same way Luau's
+// tostring does (including calling the __tostring metamethod).
+func (l *GoLuaVmWrapper) ToString(value Value) (string, error) {
+	defer l.vmState().repanic()
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return "", err
+	}
+	cValue, err := l.valueToC(value)
+	if err != nil {
+		return "", err // Return error if the value cannot be converted
+	}
+
+	res := C.luago_value_to_string(lua, cValue)
+	if res.error != nil {
+		return "", moveErrorToGoError(res.error)
+	}
+	str := &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab, l.state)}
+	defer str.Close()
+	return str.String(), nil
+}
+
+// FormatPrintArgs formats the arguments of a print call the
+// same way Luau's print does (tab separated, using tostring).
+func (l *GoLuaVmWrapper) FormatPrintArgs(args []Value) (string, error) {
+	var sb strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteByte('\t')
+		}
+		str, err := l.ToString(arg)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(str)
+	}
+	return sb.String(), nil
+}
+
+// SetPrintHandler replaces the global print function of the Lua VM so that
+// calls to print are sent to handler instead of the process's stdout.
+//
+// Only print is redirected (as are SetOutput and SetLogger, which use
+// SetPrintHandler). Luau has no warn function (unlike Lua 5.4), so scripts
+// have no other way of writing to stdout; hosts wanting a warn function
+// can create one with CreateFunction.
+//
+// Passing a nil handler restores the original print function.
+func (l *GoLuaVmWrapper) SetPrintHandler(handler PrintHandler) error {
+	original, err := l.NamedRegistryValue(originalPrintRegistryName)
+	if err != nil {
+		return err
+	}
+	defer original.Close()
+
+	if handler == nil {
+		if original.Type() == LuaValueNil {
+			return nil // print was never replaced
+		}
+		return l.SetGlobal("print", original)
+	}
+
+	if original.Type() == LuaValueNil {
+		// Save the original print so it can be restored later
+		print, err := l.GetGlobal("print")
+		if err != nil {
+			return err
+		}
+		err = l.SetNamedRegistryValue(originalPrintRegistryName, print)
+		print.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	fn, err := l.CreateFunction(func(funcVm *GoLuaVmWrapper, args []Value) ([]Value, error) {
+		handler(funcVm, args)
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer fn.Close()
+
+	return l.SetGlobal("print", fn.ToValue())
+}
+
+// SetOutput redirects print to w, writing each call to print
+// as a line formatted the same way Luau's print does.
+func (l *GoLuaVmWrapper) SetOutput(w io.Writer) error {
+	return l.SetPrintHandler(func(vm *GoLuaVmWrapper, args []Value) {
+		line, err := vm.FormatPrintArgs(args)
+		if err != nil {
+			line = "<" + err.Error() + ">"
+		}
+		io.WriteString(w, line+"\n")
+	})
+}
+
+// SetLogger redirects print to logger, logging each call
+// to print as a message at the Info level.
+func (l *GoLuaVmWrapper) SetLogger(logger *slog.Logger) error {
+	return l.SetPrintHandler(func(vm *GoLuaVmWrapper, args []Value) {
+		msg, err := vm.FormatPrintArgs(args)
+		if err != nil {
+			logger.Error("failed to format print arguments", "error", err)
+			return
+		}
+		logger.Info(msg)
+	})
+}
@@ -0,0 +1,69 @@
+package vm_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestSetOutput(t *testing.T) {
+	luaVm := newVm(t)
+
+	var buf bytes.Buffer
+	if err := luaVm.SetOutput(&buf); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	exec(t, luaVm, `print("a", 1, nil, setmetatable({}, { __tostring = function() return "obj" end }))`)
+	if got, want := buf.String(), "a\t1\tnil\tobj\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	// Restoring the original print stops the redirection
+	if err := luaVm.SetPrintHandler(nil); err != nil {
+		t.Fatalf("SetPrintHandler(nil): %v", err)
+	}
+	buf.Reset()
+	exec(t, luaVm, `print("to stdout")`)
+	if buf.Len() != 0 {
+		t.Fatalf("output after restoring print = %q", buf.String())
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	luaVm := newVm(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	if err := luaVm.SetLogger(logger); err != nil {
+		t.Fatalf("SetLogger: %v", err)
+	}
+	exec(t, luaVm, `print("hello", "world")`)
+	if got := buf.String(); !strings.Contains(got, "level=INFO") || !strings.Contains(got, `msg="hello\tworld"`) {
+		t.Fatalf("log = %q, want an info message", got)
+	}
+}
+
+func TestSetPrintHandler(t *testing.T) {
+	luaVm := newVm(t)
+
+	var got []string
+	err := luaVm.SetPrintHandler(func(v *vm.GoLuaVmWrapper, args []vm.Value) {
+		for _, arg := range args {
+			s, err := v.ToString(arg)
+			if err != nil {
+				t.Errorf("ToString: %v", err)
+			}
+			got = append(got, s)
+		}
+	})
+	if err != nil {
+		t.Fatalf("SetPrintHandler: %v", err)
+	}
+	exec(t, luaVm, `print(true, 2.5)`)
+	if strings.Join(got, ",") != "true,2.5" {
+		t.Fatalf("handler got %q, want [true 2.5]", got)
+	}
+}
@@ -0,0 +1,230 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+var registryKeyTab = objectTab{
+	name: "RegistryKey",
+	dtor: func(ptr *C.void) {
+		C.luago_free_registry_key((*C.struct_RegistryKey)(unsafe.Pointer(ptr)))
+	},
+}
+
+// A RegistryKey is a handle to a value stored in the Lua registry.
+//
+// Unlike values received in a FunctionFn callback, values in the registry
+// stay alive until they are removed, making them suitable for storing Luau
+// values (such as handler functions registered by a script) for later use.
+//
+// A RegistryKey can be used with any GoLuaVmWrapper of the Lua VM it was
+// created in (including the callback VMs passed to a FunctionFn).
+//
+// Closing a RegistryKey without removing it marks the value as expired, and it
+// is removed from the registry on a later registry operation.
+type RegistryKey struct {
+	object *object
+}
+
+func (k *RegistryKey) innerPtr() (*C.struct_RegistryKey, error) {
+	ptr, err := k.object.PointerNoLock()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+	return (*C.struct_RegistryKey)(unsafe.Pointer(ptr)), nil
+}
+
+// Close closes the RegistryKey
+func (k *RegistryKey) Close() {
+	if k == nil || k.object == nil {
+		return // Nothing to close
+	}
+	k.object.Close()
+}
+
+// CreateRegistryValue stores a value in the Lua registry, returning
+// a key that can later be used to retrieve it.
+func (l *GoLuaVmWrapper) CreateRegistryValue(value Value) (*RegistryKey, error) {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+	cValue, err := l.valueToC(value)
+	if err != nil {
+		return nil, err // Return error if the value cannot be converted
+	}
+
+	res := C.luago_create_registry_value(lua, cValue)
+	if res.error != nil {
+		return nil, moveErrorToGoError(res.error)
+	}
+	return &RegistryKey{object: newObject((*C.void)(unsafe.Pointer(res.value)), registryKeyTab, l.state)}, nil
+}
+
+// RegistryValue returns the value stored in the Lua registry under key.
+func (l *GoLuaVmWrapper) RegistryValue(key *RegistryKey) (Value, error) {
+	if key == nil {
+		return &ValueNil{}, errors.New("registry key cannot be nil")
+	}
+
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return &ValueNil{}, err
+	}
+
+	key.object.RLock()
+	defer key.object.RUnlock()
+	keyPtr, err := key.innerPtr()
+	if err != nil {
+		return &ValueNil{}, err // Return error if the key is closed
+	}
+
+	res := C.luago_registry_value(lua, keyPtr)
+	if res.error != nil {
+		return &ValueNil{}, moveErrorToGoError(res.error)
+	}
+	return l.valueFromC(res.value), nil
+}
+
+// ReplaceRegistryValue replaces the value stored in the Lua registry under key.
+func (l *GoLuaVmWrapper) ReplaceRegistryValue(key *RegistryKey, value Value) error {
+	if key == nil {
+		return errors.New("registry key cannot be nil")
+	}
+
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+
+	// Replacing mutates the key, so take the write lock
+	key.object.Lock()
+	defer key.object.Unlock()
+	keyPtr, err := key.innerPtr()
+	if err != nil {
+		return err // Return error if the key is closed
+	}
+	cValue, err := l.valueToC(value)
+	if err != nil {
+		return err // Return error if the value cannot be converted
+	}
+
+	res := C.luago_replace_registry_value(lua, keyPtr, cValue)
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// RemoveRegistryValue removes the value stored in the Lua registry under key.
+//
+// The key is closed and cannot be used afterwards.
+func (l *GoLuaVmWrapper) RemoveRegistryValue(key *RegistryKey) error {
+	if key == nil {
+		return errors.New("registry key cannot be nil")
+	}
+
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+
+	keyPtr, err := key.object.TakePointer()
+	if err != nil {
+		return err // Return error if the key is closed
+	}
+
+	// Rust takes ownership of the key
+	res := C.luago_remove_registry_value(lua, (*C.struct_RegistryKey)(unsafe.Pointer(keyPtr)))
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// SetNamedRegistryValue stores a value in the Lua registry under name.
+func (l *GoLuaVmWrapper) SetNamedRegistryValue(name string, value Value) error {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+	cValue, err := l.valueToC(value)
+	if err != nil {
+		return err // Return error if the value cannot be converted
+	}
+
+	cName, cLen := registryName(name)
+	res := C.luago_set_named_registry_value(lua, cName, cLen, cValue)
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// NamedRegistryValue returns the value stored in the Lua registry under name.
+//
+// If no value is stored under name, it returns LuaValue of nil
+func (l *GoLuaVmWrapper) NamedRegistryValue(name string) (Value, error) {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return &ValueNil{}, err
+	}
+
+	cName, cLen := registryName(name)
+	res := C.luago_named_registry_value(lua, cName, cLen)
+	if res.error != nil {
+		return &ValueNil{}, moveErrorToGoError(res.error)
+	}
+	return l.valueFromC(res.value), nil
+}
+
+// UnsetNamedRegistryValue removes the value stored in the Lua registry under name.
+func (l *GoLuaVmWrapper) UnsetNamedRegistryValue(name string) error {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+
+	cName, cLen := registryName(name)
+	res := C.luago_unset_named_registry_value(lua, cName, cLen)
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// registryName returns a pointer to the bytes of name for passing to Rust
+//
+// Rust copies the name, so the pointer only needs to live for the duration of the call
+func registryName(name string) (*C.char, C.size_t) {
+	if len(name) == 0 {
+		return nil, 0
+	}
+	return (*C.char)(unsafe.Pointer(unsafe.StringData(name))), C.size_t(len(name))
+}
@@ -0,0 +1,92 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+func TestRegistryValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	// A script registers a handler, which is kept past the callback
+	var key *vm.RegistryKey
+	register, err := luaVm.CreateFunction(func(funcVm *vm.GoLuaVmWrapper, args []vm.Value) ([]vm.Value, error) {
+		var err error
+		key, err = funcVm.CreateRegistryValue(args[0])
+		return nil, err
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer register.Close()
+	if err := luaVm.SetGlobal("register", register.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	exec(t, luaVm, `register(function(x) return x * 2 end)`)
+	if key == nil {
+		t.Fatal("handler was not registered")
+	}
+	defer key.Close()
+
+	v, err := luaVm.RegistryValue(key)
+	if err != nil {
+		t.Fatalf("RegistryValue: %v", err)
+	}
+	handler := v.(*vm.ValueFunction).Value()
+	defer handler.Close()
+	rets, err := handler.Call([]vm.Value{vm.NewValueInteger(21)})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if got := decode[int](t, luaVm, rets[0]); got != 42 {
+		t.Fatalf("handler returned %d, want 42", got)
+	}
+
+	if err := luaVm.ReplaceRegistryValue(key, vm.GoString("replaced")); err != nil {
+		t.Fatalf("ReplaceRegistryValue: %v", err)
+	}
+	v, err = luaVm.RegistryValue(key)
+	if err != nil {
+		t.Fatalf("RegistryValue: %v", err)
+	}
+	if got := decode[string](t, luaVm, v); got != "replaced" {
+		t.Fatalf("replaced value = %q, want replaced", got)
+	}
+
+	if err := luaVm.RemoveRegistryValue(key); err != nil {
+		t.Fatalf("RemoveRegistryValue: %v", err)
+	}
+	if _, err := luaVm.RegistryValue(key); err == nil {
+		t.Fatal("RegistryValue with a removed key succeeded")
+	}
+	if _, err := luaVm.RegistryValue(nil); err == nil {
+		t.Fatal("RegistryValue with a nil key succeeded")
+	}
+}
+
+func TestNamedRegistryValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	if err := luaVm.SetNamedRegistryValue("app.config", vm.NewValueInteger(7)); err != nil {
+		t.Fatalf("SetNamedRegistryValue: %v", err)
+	}
+	v, err := luaVm.NamedRegistryValue("app.config")
+	if err != nil {
+		t.Fatalf("NamedRegistryValue: %v", err)
+	}
+	if got := decode[int](t, luaVm, v); got != 7 {
+		t.Fatalf("value = %d, want 7", got)
+	}
+
+	if err := luaVm.UnsetNamedRegistryValue("app.config"); err != nil {
+		t.Fatalf("UnsetNamedRegistryValue: %v", err)
+	}
+	v, err = luaVm.NamedRegistryValue("app.config")
+	if err != nil {
+		t.Fatalf("NamedRegistryValue: %v", err)
+	}
+	if v.Type() != vm.LuaValueNil {
+		t.Fatalf("unset value = %v, want nil", v.Type())
+	}
+}
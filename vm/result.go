@@ -4,7 +4,6 @@ package vm
 #include "../rustlib/rustlib.h"
 */
 import "C"
-import "errors"
 
 func moveErrorToGo(err *C.char) string {
 	if err == nil {
@@ -15,11 +14,12 @@ func moveErrorToGo(err *C.char) string {
 	return errStr
 }
 
+// moveErrorToGoError converts an error returned by the Rust side into a *LuaError
 func moveErrorToGoError(err *C.char) error {
 	if err == nil {
 		return nil
 	}
 	errStr := C.GoString(err)
 	C.luago_result_error_free(err) // Free the error string
-	return errors.New(errStr)
+	return parseLuaError(errStr)
 }
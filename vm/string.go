@@ -10,6 +10,7 @@ import (
 import "C"
 
 var stringTab = objectTab{
+	name: "LuaString",
 	dtor: func(ptr *C.void) {
 		C.luago_free_string((*C.struct_LuaString)(unsafe.Pointer(ptr)))
 	},
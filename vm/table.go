@@ -5,12 +5,12 @@ package vm
 */
 import "C"
 import (
-	"errors"
 	"fmt"
 	"unsafe"
 )
 
 var tableTab = objectTab{
+	name: "LuaTable",
 	dtor: func(ptr *C.void) {
 		C.luago_free_table((*C.struct_LuaTable)(unsafe.Pointer(ptr)))
 	},
@@ -51,6 +51,7 @@ func (l *LuaTable) Clear() error {
 
 // ContainsKey checks if the LuaTable contains a key
 func (l *LuaTable) ContainsKey(key Value) (bool, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -75,6 +76,7 @@ func (l *LuaTable) ContainsKey(key Value) (bool, error) {
 // The two tables are first compared by reference. Otherwise,
 // the __eq metamethod may be called to compare the two tables.
 func (l *LuaTable) Equals(other *LuaTable) (bool, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -101,6 +103,7 @@ type TableForEachFn = func(key, value Value) error
 // Deadlock note: the LuaTable should not be closed while inside a ForEach loop.
 // Note 2: the returned error variant should not be closed
 func (l *LuaTable) ForEach(fn TableForEachFn) error {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -130,9 +133,7 @@ func (l *LuaTable) ForEach(fn TableForEachFn) error {
 			errv = err               // Capture the error to return it later
 			cval.stop = C.bool(true) // Stop the iteration
 		}
-	}, func() {
-		fmt.Println("foreach callback is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_table_foreach(ptr, cbWrapper.ToC())
 	if res.error != nil {
@@ -150,6 +151,7 @@ type TableForEachValueFn = func(value Value) error
 // Deadlock note: the LuaTable should not be closed while inside a ForEach loop.
 // Note 2: the returned error variant should not be closed
 func (l *LuaTable) ForEachValue(fn TableForEachValueFn) error {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -178,16 +180,11 @@ func (l *LuaTable) ForEachValue(fn TableForEachValueFn) error {
 			errv = err               // Capture the error to return it later
 			cval.stop = C.bool(true) // Stop the iteration
 		}
-	}, func() {
-		fmt.Println("foreachvalue callback is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_table_foreach_value(ptr, cbWrapper.ToC())
 	if res.error != nil {
-		errStr := moveErrorToGo(res.error)
-		if errStr != "stop" {
-			return errors.New(errStr)
-		}
+		return moveErrorToGoError(res.error)
 	}
 
 	return errv
@@ -197,6 +194,7 @@ func (l *LuaTable) ForEachValue(fn TableForEachValueFn) error {
 //
 // If the key does not exist, it returns LuaValue of nil
 func (l *LuaTable) Get(key Value) (Value, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -256,6 +254,7 @@ func (l *LuaTable) IsReadonly() bool {
 //
 // To avoid invoking the __len metamethod, use RawLen instead.
 func (l *LuaTable) Len() (int64, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -289,13 +288,14 @@ func (l *LuaTable) Metatable() *LuaTable {
 		return nil // No metatable or the table is closed
 	}
 
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res)), tableTab), lua: l.lua}
+	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res)), tableTab, l.lua.vmState()), lua: l.lua}
 }
 
 // Pop removes the last element from the LuaTable
 //
 // This might invoke the __len and __newindex metamethods.
 func (l *LuaTable) Pop() (Value, error) {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -314,6 +314,7 @@ func (l *LuaTable) Pop() (Value, error) {
 //
 // This might invoke the __len and __newindex metamethods.
 func (l *LuaTable) Push(value Value) error {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -488,6 +489,7 @@ func (l *LuaTable) RawSet(key Value, value Value) error {
 //
 // This might invoke the __newindex metamethod if it exists.
 func (l *LuaTable) Set(key Value, value Value) error {
+	defer l.lua.vmState().repanic()
 	l.object.RLock()
 	defer l.object.RUnlock()
 
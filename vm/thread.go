@@ -0,0 +1,210 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"unsafe"
+)
+
+var threadTab = objectTab{
+	name: "LuaThread",
+	dtor: func(ptr *C.void) {
+		C.luago_free_thread((*C.struct_LuaThread)(unsafe.Pointer(ptr)))
+	},
+}
+
+// ThreadStatus is the status of a LuaThread
+type ThreadStatus int
+
+const (
+	ThreadStatusResumable ThreadStatus = 0 // The thread can be resumed (not started yet or yielded)
+	ThreadStatusRunning   ThreadStatus = 1 // The thread is currently running
+	ThreadStatusNormal    ThreadStatus = 2 // The thread is running but has resumed another thread
+	ThreadStatusFinished  ThreadStatus = 3 // The thread has returned
+	ThreadStatusError     ThreadStatus = 4 // The thread has errored
+)
+
+func (s ThreadStatus) String() string {
+	switch s {
+	case ThreadStatusResumable:
+		return "resumable"
+	case ThreadStatusRunning:
+		return "running"
+	case ThreadStatusNormal:
+		return "normal"
+	case ThreadStatusFinished:
+		return "finished"
+	case ThreadStatusError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// A LuaThread is an abstraction over a Lua thread (coroutine).
+type LuaThread struct {
+	lua    *GoLuaVmWrapper // The Lua VM wrapper that owns this thread
+	object *object
+}
+
+func (l *LuaThread) innerPtr() (*C.struct_LuaThread, error) {
+	ptr, err := l.object.PointerNoLock()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+	return (*C.struct_LuaThread)(unsafe.Pointer(ptr)), nil
+}
+
+// CreateThread creates a new LuaThread (coroutine) that runs fn when first resumed.
+func (l *GoLuaVmWrapper) CreateThread(fn *LuaFunction) (*LuaThread, error) {
+	if fn == nil {
+		return nil, errors.New("function cannot be nil")
+	}
+
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	fn.object.RLock()
+	defer fn.object.RUnlock()
+	fnPtr, err := fn.innerPtr()
+	if err != nil {
+		return nil, err // Return error if the function is closed
+	}
+
+	res := C.luago_create_thread(lua, fnPtr)
+	if res.error != nil {
+		return nil, moveErrorToGoError(res.error)
+	}
+	return &LuaThread{object: newObject((*C.void)(unsafe.Pointer(res.value)), threadTab, l.state), lua: l.owner()}, nil
+}
+
+// Resume resumes the thread with args, running it until it yields
+// or returns, and returns the yielded/returned values.
+//
+// When starting the thread, args are passed to its function. When resuming a
+// yielded thread, args are returned by the coroutine.yield call in Luau.
+func (l *LuaThread) Resume(args []Value) ([]Value, error) {
+	defer l.lua.vmState().repanic()
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+
+	var rets []Value
+	_, err = l.lua.meter(context.Background(), func(call *C.struct_CallState) error {
+		mw, err := l.lua.multiValueFromValues(args)
+		if err != nil {
+			return err // Return error if the value cannot be converted
+		}
+
+		// Threads owned by a closed (callback) VM cannot be resumed (see LuaFunction.call)
+		l.lua.obj.RLock()
+		defer l.lua.obj.RUnlock()
+		lua, err := l.lua.lua()
+		if err != nil {
+			mw.close()
+			return err
+		}
+
+		res := C.luago_thread_resume(lua, ptr, mw.ptr, call)
+		rets, err = l.lua.takeCallResult(res)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rets, nil
+}
+
+// Status returns the status of the thread.
+//
+// A closed thread is reported as ThreadStatusError.
+func (l *LuaThread) Status() ThreadStatus {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return ThreadStatusError
+	}
+
+	l.lua.obj.RLock()
+	defer l.lua.obj.RUnlock()
+	lua, err := l.lua.lua()
+	if err != nil {
+		return ThreadStatusError
+	}
+
+	return ThreadStatus(C.luago_thread_status(lua, ptr))
+}
+
+// Reset resets the thread so that it runs fn when next resumed,
+// allowing a finished (or errored) thread to be reused.
+//
+// Luau cannot reset a running thread.
+func (l *LuaThread) Reset(fn *LuaFunction) error {
+	if fn == nil {
+		return errors.New("function cannot be nil")
+	}
+
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return err // Return error if the object is closed
+	}
+
+	fn.object.RLock()
+	defer fn.object.RUnlock()
+	fnPtr, err := fn.innerPtr()
+	if err != nil {
+		return err // Return error if the function is closed
+	}
+
+	res := C.luago_thread_reset(ptr, fnPtr)
+	if res.error != nil {
+		return moveErrorToGoError(res.error)
+	}
+	return nil
+}
+
+// Returns a 'pointer' to a Lua-owned thread
+//
+// This pointer is only useful for hashing/debugging
+// and cannot be converted back to the original Lua thread object.
+func (l *LuaThread) Pointer() uint64 {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return 0 // Return 0 if the object is closed
+	}
+	return uint64(C.luago_thread_to_pointer(ptr))
+}
+
+// ToValue converts the LuaThread to a Value.
+func (l *LuaThread) ToValue() Value {
+	return &ValueThread{value: l}
+}
+
+func (l *LuaThread) Close() {
+	if l == nil || l.object == nil {
+		return // Nothing to close
+	}
+	// Close the LuaThread object
+	l.object.Close()
+}
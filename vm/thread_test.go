@@ -0,0 +1,225 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// newThread creates a thread running the function returned by code
+func newThread(t *testing.T, luaVm *vm.GoLuaVmWrapper, code string) *vm.LuaThread {
+	t.Helper()
+	rets := exec(t, luaVm, code)
+	fn := rets[0].(*vm.ValueFunction).Value()
+	defer fn.Close()
+	thread, err := luaVm.CreateThread(fn)
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	t.Cleanup(thread.Close)
+	return thread
+}
+
+func TestThreadResume(t *testing.T) {
+	luaVm := newVm(t)
+	thread := newThread(t, luaVm, `return function(a)
+		local b = coroutine.yield(a + 1)
+		return b * 2
+	end`)
+
+	rets, err := thread.Resume([]vm.Value{vm.NewValueInteger(1)})
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if got := decode[int](t, luaVm, rets[0]); got != 2 {
+		t.Fatalf("yielded %d, want 2", got)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusResumable {
+		t.Fatalf("Status = %v, want resumable", s)
+	}
+
+	rets, err = thread.Resume([]vm.Value{vm.NewValueInteger(5)})
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if got := decode[int](t, luaVm, rets[0]); got != 10 {
+		t.Fatalf("returned %d, want 10", got)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusFinished {
+		t.Fatalf("Status = %v, want finished", s)
+	}
+}
+
+func TestThreadResumeError(t *testing.T) {
+	luaVm := newVm(t)
+	thread := newThread(t, luaVm, `return function()
+		error({ code = 7 })
+	end`)
+
+	_, err := thread.Resume(nil)
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %#v, want *LuaError", err)
+	}
+	if luaErr.Kind != vm.LuaErrorRuntime {
+		t.Fatalf("Kind = %v, want runtime", luaErr.Kind)
+	}
+	// The raised value reaches Go intact
+	tbl, ok := luaErr.Value().(*vm.ValueTable)
+	if !ok {
+		t.Fatalf("Value = %#v, want table", luaErr.Value())
+	}
+	code, err := tbl.Value().Get(vm.GoString("code"))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := decode[int](t, luaVm, code); got != 7 {
+		t.Fatalf("code = %d, want 7", got)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusError {
+		t.Fatalf("Status = %v, want error", s)
+	}
+}
+
+func TestThreadResumeGoError(t *testing.T) {
+	luaVm := newVm(t)
+	setFailing(t, luaVm)
+	thread := newThread(t, luaVm, `return function()
+		fail()
+	end`)
+
+	_, err := thread.Resume(nil)
+	if !errors.Is(err, errSentinel) {
+		t.Fatalf("err = %v, want errSentinel", err)
+	}
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) || luaErr.Kind != vm.LuaErrorCallback {
+		t.Fatalf("err = %#v, want callback LuaError", err)
+	}
+	if !strings.Contains(luaErr.Traceback, "test") {
+		t.Fatalf("Traceback = %q, want the frames of the thread", luaErr.Traceback)
+	}
+}
+
+func TestThreadStatusRunning(t *testing.T) {
+	luaVm := newVm(t)
+
+	var thread *vm.LuaThread
+	var inner, outer vm.ThreadStatus
+	check, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		outer = thread.Status()
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer check.Close()
+	if err := luaVm.SetGlobal("check", check.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	checkInner, err := luaVm.CreateFunction(func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		inner = thread.Status()
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer checkInner.Close()
+	if err := luaVm.SetGlobal("checkInner", checkInner.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+
+	thread = newThread(t, luaVm, `return function()
+		check()
+		coroutine.wrap(checkInner)()
+	end`)
+	if _, err := thread.Resume(nil); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if outer != vm.ThreadStatusRunning {
+		t.Fatalf("Status while running = %v, want running", outer)
+	}
+	if inner != vm.ThreadStatusNormal {
+		t.Fatalf("Status while resuming another thread = %v, want normal", inner)
+	}
+}
+
+func TestThreadReset(t *testing.T) {
+	luaVm := newVm(t)
+	thread := newThread(t, luaVm, `return function() return 1 end`)
+
+	if _, err := thread.Resume(nil); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusFinished {
+		t.Fatalf("Status = %v, want finished", s)
+	}
+
+	rets := exec(t, luaVm, `return function() return 2 end`)
+	fn := rets[0].(*vm.ValueFunction).Value()
+	defer fn.Close()
+	if err := thread.Reset(fn); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusResumable {
+		t.Fatalf("Status after Reset = %v, want resumable", s)
+	}
+	rets, err := thread.Resume(nil)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if got := decode[int](t, luaVm, rets[0]); got != 2 {
+		t.Fatalf("returned %d, want 2", got)
+	}
+	if err := thread.Reset(nil); err == nil {
+		t.Fatal("Reset with a nil function succeeded")
+	}
+}
+
+func TestThreadValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	// A coroutine created by a script can be driven from Go
+	rets := exec(t, luaVm, `return coroutine.create(function() coroutine.yield("first") end)`)
+	tv, ok := rets[0].(*vm.ValueThread)
+	if !ok {
+		t.Fatalf("ret = %#v, want *ValueThread", rets[0])
+	}
+	thread := tv.Value()
+	defer thread.Close()
+	rets, err := thread.Resume(nil)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if got := decode[string](t, luaVm, rets[0]); got != "first" {
+		t.Fatalf("yielded %q, want first", got)
+	}
+
+	// And passed back to Luau
+	if err := luaVm.SetGlobal("co", thread.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+	rets = exec(t, luaVm, `coroutine.resume(co) return coroutine.status(co)`)
+	if got := decode[string](t, luaVm, rets[0]); got != "dead" {
+		t.Fatalf("coroutine.status = %q, want dead", got)
+	}
+	if s := thread.Status(); s != vm.ThreadStatusFinished {
+		t.Fatalf("Status = %v, want finished", s)
+	}
+}
+
+func TestThreadValueClosed(t *testing.T) {
+	luaVm := newVm(t)
+
+	// Zero and closed threads are rejected instead of panicking
+	if err := luaVm.SetGlobal("co", &vm.ValueThread{}); !errors.Is(err, vm.ErrClosed) {
+		t.Fatalf("SetGlobal with a zero ValueThread = %v, want ErrClosed", err)
+	}
+	thread := newThread(t, luaVm, `return function() end`)
+	thread.Close()
+	if err := luaVm.SetGlobal("co", thread.ToValue()); err == nil {
+		t.Fatal("SetGlobal with a closed thread succeeded")
+	}
+}
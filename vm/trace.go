@@ -0,0 +1,99 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// callers returns the program counters of the stack of the calling
+// function, skipping skip additional frames
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 128)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+// StackTrace returns a combined stack trace of the error, interleaving the
+// Go frames and the Luau frames of each call into Luau, innermost first:
+//
+//	main.lookup
+//		/src/main.go:21
+//	[string "script"]:3 function fetch
+//	[string "script"]:8
+//	main.main
+//		/src/main.go:40
+//
+// Go frames are only known for errors raised by Go callbacks (including
+// panics, see CallbackPanic), other errors only have their Luau frames.
+func (e *LuaError) StackTrace() string {
+	// Luau tracebacks of each call into Luau, innermost first
+	var luau []string
+	// Go stack of the innermost Go callback
+	var pcs []uintptr
+
+	var err error = e
+	for err != nil {
+		switch v := err.(type) {
+		case *LuaError:
+			luau = append([]string{v.Traceback}, luau...)
+			if v.pcs != nil {
+				pcs = v.pcs
+			}
+			err = v.cause
+			continue
+		case *CallbackPanic:
+			pcs = v.pcs
+		}
+		err = errors.Unwrap(err)
+	}
+
+	var b strings.Builder
+	writeLuau := func(traceback string) {
+		traceback = strings.TrimPrefix(traceback, "stack traceback:")
+		for _, line := range strings.Split(traceback, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				b.WriteString(line)
+				b.WriteByte('\n')
+			}
+		}
+	}
+
+	if len(pcs) > 0 {
+		frames := runtime.CallersFrames(pcs)
+		for {
+			frame, more := frames.Next()
+			switch {
+			case frame.Function == "runtime.gopanic":
+				// Frames before are those of recovering the panic
+				b.Reset()
+			case frame.Function == "runtime.cgocallbackg":
+				// Luau called into Go here
+				if len(luau) > 0 {
+					writeLuau(luau[0])
+					luau = luau[1:]
+				}
+			case !isGlueFrame(frame.Function):
+				fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	for _, traceback := range luau {
+		writeLuau(traceback)
+	}
+	return b.String()
+}
+
+// isGlueFrame returns if the function of a Go frame is part of the
+// runtime or cgo glue between Go and Luau
+func isGlueFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") ||
+		strings.HasPrefix(function, "_cgoexp_") ||
+		strings.Contains(function, "._Cfunc_") ||
+		strings.Contains(function, "._cgoexp_") ||
+		strings.HasSuffix(function, "/vm.goCallbackTrampoline")
+}
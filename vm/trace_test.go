@@ -0,0 +1,174 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// setGlobalFunc sets the global name to a function calling fn
+func setGlobalFunc(t *testing.T, luaVm *vm.GoLuaVmWrapper, name string, fn vm.FunctionFn) {
+	t.Helper()
+	f, err := luaVm.CreateFunction(fn)
+	if err != nil {
+		t.Fatalf("CreateFunction: %v", err)
+	}
+	defer f.Close()
+	if err := luaVm.SetGlobal(name, f.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	luaVm := newVm(t)
+	setGlobalFunc(t, luaVm, "lookup", func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		return nil, errSentinel
+	})
+
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "script", Code: `
+		local function fetch()
+			lookup()
+		end
+		fetch()
+	`})
+	var luaErr *vm.LuaError
+	if !errors.As(err, &luaErr) {
+		t.Fatalf("err = %v, want *LuaError", err)
+	}
+	trace := luaErr.StackTrace()
+
+	// Go frames of the callback, then the Luau frames calling it (past the
+	// frames of the wrapper calling the callback), then the Go caller
+	want := []string{"TestStackTrace.func1", "function fetch", "TestStackTrace\n"}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(trace, w)
+		if i < 0 {
+			t.Fatalf("trace has no %q:\n%s", w, trace)
+		}
+		if i < last {
+			t.Fatalf("%q out of order in trace:\n%s", w, trace)
+		}
+		last = i
+	}
+	for _, line := range strings.Split(trace, "\n") {
+		if strings.HasPrefix(line, "gluau:") {
+			t.Fatalf("trace contains internal frames:\n%s", trace)
+		}
+	}
+}
+
+func TestPanicAsError(t *testing.T) {
+	luaVm := newVm(t)
+	setGlobalFunc(t, luaVm, "boom", func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		panic(errSentinel)
+	})
+
+	_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `boom()`})
+	var p *vm.CallbackPanic
+	if !errors.As(err, &p) || p.Value != errSentinel || len(p.Stack) == 0 {
+		t.Fatalf("err = %v, want *CallbackPanic", err)
+	}
+	if !errors.Is(err, errSentinel) {
+		t.Fatalf("err = %v, want errSentinel", err)
+	}
+
+	// Scripts can catch the panic like any error
+	rets := exec(t, luaVm, `return pcall(boom)`)
+	if ok := decode[bool](t, luaVm, rets[0]); ok {
+		t.Fatal("pcall(boom) succeeded")
+	}
+}
+
+func TestPanicRepanic(t *testing.T) {
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibAllSafe, PanicPolicy: vm.PanicRepanic})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+	defer luaVm.Close()
+	setGlobalFunc(t, luaVm, "boom", func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		panic("boom")
+	})
+
+	defer func() {
+		p, ok := recover().(*vm.CallbackPanic)
+		if !ok || p.Value != "boom" {
+			t.Fatalf("recovered %v, want *CallbackPanic", p)
+		}
+	}()
+	// Re-panics even though the script catches the error
+	luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `pcall(boom)`})
+	t.Fatal("ExecChunk returned")
+}
+
+func TestPanicRepanicMetamethod(t *testing.T) {
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibAllSafe, PanicPolicy: vm.PanicRepanic})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+	defer luaVm.Close()
+	setGlobalFunc(t, luaVm, "boom", func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		panic("boom")
+	})
+
+	rets := exec(t, luaVm, `return setmetatable({}, { __index = function(_, k) pcall(boom) return k end })`)
+	tab := rets[0].(*vm.ValueTable).Value()
+	defer tab.Close()
+
+	func() {
+		defer func() {
+			if p, ok := recover().(*vm.CallbackPanic); !ok || p.Value != "boom" {
+				t.Errorf("recovered %v, want *CallbackPanic", p)
+			}
+		}()
+		// Re-panics from the __index metamethod called by Get
+		tab.Get(vm.GoString("key"))
+		t.Error("Get returned")
+	}()
+
+	// The panic was raised by Get and does not go off in later calls
+	rets = exec(t, luaVm, `return 1`)
+	if got := decode[int](t, luaVm, rets[0]); got != 1 {
+		t.Errorf("later call returned %d, want 1", got)
+	}
+}
+
+func TestPanicCallHook(t *testing.T) {
+	var hooked *vm.CallbackPanic
+	luaVm, err := vm.CreateLuaVmWithOptions(vm.VmOptions{
+		StdLibs:     vm.StdLibAllSafe,
+		PanicPolicy: vm.PanicCallHook,
+		PanicHook: func(p *vm.CallbackPanic) error {
+			hooked = p
+			return errSentinel
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateLuaVmWithOptions: %v", err)
+	}
+	defer luaVm.Close()
+	setGlobalFunc(t, luaVm, "boom", func(*vm.GoLuaVmWrapper, []vm.Value) ([]vm.Value, error) {
+		panic("boom")
+	})
+
+	_, err = luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `boom()`})
+	if !errors.Is(err, errSentinel) {
+		t.Fatalf("err = %v, want the error returned by the hook", err)
+	}
+	if hooked == nil || hooked.Value != "boom" {
+		t.Fatalf("hook called with %v, want the panic", hooked)
+	}
+	// Panics in metamethods called from Go are handled the same way
+	rets := exec(t, luaVm, `return setmetatable({}, { __index = boom })`)
+	tab := rets[0].(*vm.ValueTable).Value()
+	defer tab.Close()
+	hooked = nil
+	if _, err := tab.Get(vm.GoString("key")); err == nil || !strings.Contains(err.Error(), "sentinel") {
+		t.Fatalf("Get err = %v, want the error returned by the hook", err)
+	}
+	if hooked == nil || hooked.Value != "boom" {
+		t.Fatalf("hook called with %v, want the panic from __index", hooked)
+	}
+}
@@ -0,0 +1,596 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// FromValue decodes a Luau value into the Go value pointed to by target,
+// in the spirit of json.Unmarshal.
+//
+// The decoding rules are the reverse of ToValue:
+//
+//   - booleans decode into bools
+//   - integers and numbers decode into any Go integer or float type, as long
+//     as the value is integral (for integer targets) and fits the target type
+//   - strings decode into strings and []byte (buffers also decode into []byte)
+//   - array-like tables (with keys 1..n) decode into slices and arrays
+//   - tables decode into maps, with keys and values decoded using the same rules
+//   - tables decode into structs, with fields looked up by name (honouring
+//     `lua` struct tags as described in ToValue)
+//   - nil leaves the target unchanged, unless it is a pointer, map, slice or
+//     interface, in which case the target is set to nil
+//   - pointers are allocated as needed
+//
+// Targets implementing LuaUnmarshaler (and types with a converter registered
+// using RegisterConverter) are decoded using their custom conversion.
+//
+// Targets of type Value (or *LuaTable, *LuaFunction etc.) receive the
+// Luau value as-is. Decoding into an empty interface produces bool, int64,
+// float64, string, []any (for array-like tables), map[string]any (for
+// tables with only string keys) or map[any]any, with any other Luau value
+// being stored as a Value.
+//
+// Errors are qualified with the path of the value that could not be decoded
+// (for example "config.servers[2].port: expected integer, got string"), where
+// indices are those of the Luau table (starting at 1). Cyclic tables
+// cannot be decoded and result in an error.
+//
+// v is not closed by FromValue.
+func (l *GoLuaVmWrapper) FromValue(v Value, target any) error {
+	if v == nil {
+		return errors.New("cannot decode nil Value")
+	}
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+
+	d := &decoder{lua: l}
+	_, err := d.decode(v, rv.Elem(), "")
+	return err
+}
+
+var (
+	luaStringType   = reflect.TypeOf((*LuaString)(nil))
+	luaTableType    = reflect.TypeOf((*LuaTable)(nil))
+	luaFunctionType = reflect.TypeOf((*LuaFunction)(nil))
+	luaUserDataType = reflect.TypeOf((*LuaUserData)(nil))
+	luaThreadType   = reflect.TypeOf((*LuaThread)(nil))
+	luaBufferType   = reflect.TypeOf((*LuaBuffer)(nil))
+)
+
+type decoder struct {
+	lua *GoLuaVmWrapper
+	// Pointers of the tables currently being decoded, for cycle detection
+	visiting map[uint64]struct{}
+}
+
+// enter marks tab as being decoded, returning an error if it already is.
+// The returned function must be called once tab has been decoded.
+func (d *decoder) enter(tab *LuaTable, path string) (func(), error) {
+	ptr := tab.Pointer()
+	if _, ok := d.visiting[ptr]; ok {
+		return nil, pathError(path, "cyclic table")
+	}
+	if d.visiting == nil {
+		d.visiting = map[uint64]struct{}{}
+	}
+	d.visiting[ptr] = struct{}{}
+	return func() { delete(d.visiting, ptr) }, nil
+}
+
+// typeError returns an error for when v cannot be decoded into a Go value of kind expected
+func typeError(path, expected string, v Value) error {
+	return pathError(path, "expected %s, got %s", expected, v.Type())
+}
+
+// tablePair is a key-value pair of a table
+type tablePair struct {
+	key   Value
+	value Value
+
+	// Whether the key/value was stored in the decoded Go value
+	// (and hence must not be closed)
+	keyRetained   bool
+	valueRetained bool
+}
+
+// tablePairs returns the key-value pairs of a table.
+//
+// The pairs are collected before decoding so that the table
+// is not used re-entrantly while iterating over it.
+func tablePairs(tab *LuaTable) ([]tablePair, error) {
+	var pairs []tablePair
+	err := tab.ForEach(func(key, value Value) error {
+		pairs = append(pairs, tablePair{key: key, value: value})
+		return nil
+	})
+	if err != nil {
+		closePairs(pairs)
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// closePairs closes the keys and values of pairs that were not retained
+func closePairs(pairs []tablePair) {
+	for _, p := range pairs {
+		if !p.keyRetained {
+			p.key.Close()
+		}
+		if !p.valueRetained {
+			p.value.Close()
+		}
+	}
+}
+
+// arrayIndex returns the array index of a table key (if it is a positive integer)
+func arrayIndex(key Value) (int64, bool) {
+	switch k := key.(type) {
+	case *ValueInteger:
+		return k.value, k.value >= 1
+	case *ValueNumber:
+		if k.value >= 1 && k.value < math.MaxInt64 && k.value == math.Trunc(k.value) {
+			return int64(k.value), true
+		}
+	}
+	return 0, false
+}
+
+// arrayOrder returns the indices into pairs of the elements of an
+// array-like table (with keys 1..n) in order.
+//
+// ok is false if the table is not array-like.
+func arrayOrder(pairs []tablePair) (order []int, ok bool) {
+	order = make([]int, len(pairs))
+	for i := range order {
+		order[i] = -1
+	}
+	for i, p := range pairs {
+		idx, isIdx := arrayIndex(p.key)
+		if !isIdx || idx > int64(len(pairs)) || order[idx-1] != -1 {
+			return nil, false
+		}
+		order[idx-1] = i
+	}
+	return order, true
+}
+
+// stringValue returns the string held by v (if v is a string)
+func stringValue(v Value) (string, bool) {
+	switch s := v.(type) {
+	case *ValueString:
+		return s.value.String(), true
+	case GoString:
+		return string(s), true
+	}
+	return "", false
+}
+
+// keyPath returns the path of the value stored under key in the table at path
+func keyPath(path string, key Value) string {
+	if s, ok := stringValue(key); ok {
+		return fieldPath(path, s)
+	}
+	switch k := key.(type) {
+	case *ValueInteger:
+		return path + fmt.Sprintf("[%d]", k.value)
+	case *ValueNumber:
+		return path + fmt.Sprintf("[%g]", k.value)
+	case *ValueBoolean:
+		return path + fmt.Sprintf("[%t]", k.value)
+	}
+	return path + "[" + key.Type().String() + "]"
+}
+
+// decode decodes v into rv. retained is true if v (or a value
+// referencing its underlying object) was stored in rv, in which
+// case v must not be closed by the caller.
+func (d *decoder) decode(v Value, rv reflect.Value, path string) (retained bool, err error) {
+	// Values and Lua objects are stored as-is
+	switch rv.Type() {
+	case valueType:
+		rv.Set(reflect.ValueOf(v))
+		return true, nil
+	case luaStringType:
+		return d.decodeObject(v, rv, path, LuaValueString)
+	case luaTableType:
+		return d.decodeObject(v, rv, path, LuaValueTable)
+	case luaFunctionType:
+		return d.decodeObject(v, rv, path, LuaValueFunction)
+	case luaUserDataType:
+		return d.decodeObject(v, rv, path, LuaValueUserData)
+	case luaThreadType:
+		return d.decodeObject(v, rv, path, LuaValueThread)
+	case luaBufferType:
+		return d.decodeObject(v, rv, path, LuaValueBuffer)
+	}
+
+	if handled, retained, err := d.lua.unmarshalCustom(v, rv, path); handled {
+		return retained, err
+	}
+
+	if v.Type() == LuaValueNil {
+		switch rv.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return false, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(v, rv.Elem(), path)
+	case reflect.Interface:
+		if rv.NumMethod() == 0 {
+			val, retained, err := d.decodeAny(v, path)
+			if err != nil {
+				return false, err
+			}
+			if val != nil {
+				rv.Set(reflect.ValueOf(val))
+			}
+			return retained, nil
+		}
+		if reflect.TypeOf(v).Implements(rv.Type()) {
+			rv.Set(reflect.ValueOf(v))
+			return true, nil
+		}
+		return false, pathError(path, "cannot decode %s into %s", v.Type(), rv.Type())
+	case reflect.Bool:
+		b, ok := v.(*ValueBoolean)
+		if !ok {
+			return false, typeError(path, "boolean", v)
+		}
+		rv.SetBool(b.value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := decodeInteger(v, path)
+		if err != nil {
+			return false, err
+		}
+		if rv.OverflowInt(i) {
+			return false, pathError(path, "integer %d overflows %s", i, rv.Type())
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := decodeInteger(v, path)
+		if err != nil {
+			return false, err
+		}
+		if i < 0 || rv.OverflowUint(uint64(i)) {
+			return false, pathError(path, "integer %d overflows %s", i, rv.Type())
+		}
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch n := v.(type) {
+		case *ValueInteger:
+			f = float64(n.value)
+		case *ValueNumber:
+			f = n.value
+		default:
+			return false, typeError(path, "number", v)
+		}
+		if rv.OverflowFloat(f) {
+			return false, pathError(path, "number %g overflows %s", f, rv.Type())
+		}
+		rv.SetFloat(f)
+	case reflect.String:
+		s, ok := stringValue(v)
+		if !ok {
+			return false, typeError(path, "string", v)
+		}
+		rv.SetString(s)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := stringValue(v); ok {
+				rv.SetBytes([]byte(s))
+				return false, nil
+			}
+			if b, ok := v.(*ValueBuffer); ok {
+				rv.SetBytes(b.value.Bytes())
+				return false, nil
+			}
+		}
+		return d.decodeArray(v, rv, path)
+	case reflect.Array:
+		return d.decodeArray(v, rv, path)
+	case reflect.Map:
+		return d.decodeMap(v, rv, path)
+	case reflect.Struct:
+		return d.decodeStruct(v, rv, path)
+	default:
+		return false, pathError(path, "cannot decode into Go value of type %s", rv.Type())
+	}
+	return false, nil
+}
+
+// decodeObject stores the Lua object held by v in rv (which must be a pointer to the object type)
+func (d *decoder) decodeObject(v Value, rv reflect.Value, path string, typ LuaValueType) (bool, error) {
+	if v.Type() == LuaValueNil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return false, nil
+	}
+
+	var obj any
+	switch val := v.(type) {
+	case *ValueString:
+		obj = val.value
+	case *ValueTable:
+		obj = val.value
+	case *ValueFunction:
+		obj = val.value
+	case *ValueUserData:
+		obj = val.value
+	case *ValueThread:
+		obj = val.value
+	case *ValueBuffer:
+		obj = val.value
+	}
+	if v.Type() != typ || obj == nil {
+		return false, typeError(path, typ.String(), v)
+	}
+	rv.Set(reflect.ValueOf(obj))
+	return true, nil
+}
+
+// decodeInteger returns the integer held by v, which may be an integer or
+// an integral number
+func decodeInteger(v Value, path string) (int64, error) {
+	switch n := v.(type) {
+	case *ValueInteger:
+		return n.value, nil
+	case *ValueNumber:
+		if n.value != math.Trunc(n.value) || math.IsInf(n.value, 0) {
+			return 0, pathError(path, "number %g is not an integer", n.value)
+		}
+		if n.value < math.MinInt64 || n.value >= math.MaxInt64 {
+			return 0, pathError(path, "number %g overflows int64", n.value)
+		}
+		return int64(n.value), nil
+	}
+	return 0, typeError(path, "integer", v)
+}
+
+func (d *decoder) decodeArray(v Value, rv reflect.Value, path string) (bool, error) {
+	tab, ok := v.(*ValueTable)
+	if !ok {
+		return false, typeError(path, "table", v)
+	}
+	leave, err := d.enter(tab.value, path)
+	if err != nil {
+		return false, err
+	}
+	defer leave()
+
+	pairs, err := tablePairs(tab.value)
+	if err != nil {
+		return false, pathError(path, "%w", err)
+	}
+	defer closePairs(pairs)
+
+	order, ok := arrayOrder(pairs)
+	if !ok {
+		return false, pathError(path, "expected array-like table, got table with non-sequential keys")
+	}
+
+	if rv.Kind() == reflect.Array {
+		if len(order) > rv.Len() {
+			return false, pathError(path, "table has %d elements, which does not fit in %s", len(order), rv.Type())
+		}
+		// Zero the remaining elements
+		for i := len(order); i < rv.Len(); i++ {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+		}
+	} else {
+		rv.Set(reflect.MakeSlice(rv.Type(), len(order), len(order)))
+	}
+
+	for i, pi := range order {
+		p := &pairs[pi]
+		retained, err := d.decode(p.value, rv.Index(i), indexPath(path, i+1))
+		if err != nil {
+			return false, err
+		}
+		p.valueRetained = retained
+	}
+	return false, nil
+}
+
+func (d *decoder) decodeMap(v Value, rv reflect.Value, path string) (bool, error) {
+	tab, ok := v.(*ValueTable)
+	if !ok {
+		return false, typeError(path, "table", v)
+	}
+	leave, err := d.enter(tab.value, path)
+	if err != nil {
+		return false, err
+	}
+	defer leave()
+
+	pairs, err := tablePairs(tab.value)
+	if err != nil {
+		return false, pathError(path, "%w", err)
+	}
+	defer closePairs(pairs)
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), len(pairs)))
+	}
+
+	keyType := rv.Type().Key()
+	elemType := rv.Type().Elem()
+	for i := range pairs {
+		p := &pairs[i]
+		elemPath := keyPath(path, p.key)
+
+		key := reflect.New(keyType).Elem()
+		retained, err := d.decode(p.key, key, "")
+		if err != nil {
+			return false, pathError(elemPath, "invalid key: %w", err)
+		}
+		p.keyRetained = retained
+
+		elem := reflect.New(elemType).Elem()
+		retained, err = d.decode(p.value, elem, elemPath)
+		if err != nil {
+			return false, err
+		}
+		p.valueRetained = retained
+		rv.SetMapIndex(key, elem)
+	}
+	return false, nil
+}
+
+func (d *decoder) decodeStruct(v Value, rv reflect.Value, path string) (bool, error) {
+	tab, ok := v.(*ValueTable)
+	if !ok {
+		return false, typeError(path, "table", v)
+	}
+	leave, err := d.enter(tab.value, path)
+	if err != nil {
+		return false, err
+	}
+	defer leave()
+
+	for _, f := range structFields(rv.Type()) {
+		fv, ok := allocFieldByIndex(rv, f.index)
+		if !ok {
+			continue // Unexported embedded pointer which cannot be allocated
+		}
+
+		elemPath := fieldPath(path, f.name)
+		value, err := tab.value.Get(GoString(f.name))
+		if err != nil {
+			return false, pathError(elemPath, "%w", err)
+		}
+
+		retained, err := d.decode(value, fv, elemPath)
+		if !retained {
+			value.Close()
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// allocFieldByIndex is like reflect.Value.FieldByIndex but allocates
+// nil embedded struct pointers.
+func allocFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// decodeAny decodes v into its natural Go representation
+func (d *decoder) decodeAny(v Value, path string) (val any, retained bool, err error) {
+	switch val := v.(type) {
+	case *ValueNil:
+		return nil, false, nil
+	case *ValueBoolean:
+		return val.value, false, nil
+	case *ValueInteger:
+		return val.value, false, nil
+	case *ValueNumber:
+		return val.value, false, nil
+	case *ValueString:
+		return val.value.String(), false, nil
+	case GoString:
+		return string(val), false, nil
+	case *ValueTable:
+		return d.decodeAnyTable(val, path)
+	}
+	// Everything else is stored as-is
+	return v, true, nil
+}
+
+func (d *decoder) decodeAnyTable(tab *ValueTable, path string) (any, bool, error) {
+	leave, err := d.enter(tab.value, path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer leave()
+
+	pairs, err := tablePairs(tab.value)
+	if err != nil {
+		return nil, false, pathError(path, "%w", err)
+	}
+	defer closePairs(pairs)
+
+	if order, ok := arrayOrder(pairs); ok && len(order) > 0 {
+		arr := make([]any, len(order))
+		for i, pi := range order {
+			p := &pairs[pi]
+			elem, retained, err := d.decodeAny(p.value, indexPath(path, i+1))
+			if err != nil {
+				return nil, false, err
+			}
+			p.valueRetained = retained
+			arr[i] = elem
+		}
+		return arr, false, nil
+	}
+
+	allStrings := true
+	for _, p := range pairs {
+		if _, ok := stringValue(p.key); !ok {
+			allStrings = false
+			break
+		}
+	}
+
+	if allStrings {
+		m := make(map[string]any, len(pairs))
+		for i := range pairs {
+			p := &pairs[i]
+			key, _ := stringValue(p.key)
+			elem, retained, err := d.decodeAny(p.value, fieldPath(path, key))
+			if err != nil {
+				return nil, false, err
+			}
+			p.valueRetained = retained
+			m[key] = elem
+		}
+		return m, false, nil
+	}
+
+	m := make(map[any]any, len(pairs))
+	for i := range pairs {
+		p := &pairs[i]
+		elemPath := keyPath(path, p.key)
+		key, retained, err := d.decodeAny(p.key, elemPath)
+		if err != nil {
+			return nil, false, err
+		}
+		p.keyRetained = retained
+		if !reflect.TypeOf(key).Comparable() {
+			return nil, false, pathError(elemPath, "table key of type %s cannot be used as a map key", p.key.Type())
+		}
+
+		elem, retained, err := d.decodeAny(p.value, elemPath)
+		if err != nil {
+			return nil, false, err
+		}
+		p.valueRetained = retained
+		m[key] = elem
+	}
+	return m, false, nil
+}
@@ -0,0 +1,125 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+type server struct {
+	Host string `lua:"host"`
+	Port uint16 `lua:"port"`
+}
+
+type config struct {
+	Name    string
+	Servers []server `lua:"servers"`
+	Weights map[string]float64
+	Extra   any
+	Opt     *int
+	Skipped string `lua:"-"`
+}
+
+func TestFromValue(t *testing.T) {
+	luaVm := newVm(t)
+
+	rets := exec(t, luaVm, `return {
+		Name = "prod",
+		servers = { { host = "a", port = 80 }, { host = "b", port = 8080.0 } },
+		Weights = { x = 1, y = 0.5 },
+		Extra = { "one", true, nested = "x" },
+		Opt = 3,
+		Skipped = "ignored",
+	}`)
+	var cfg config
+	if err := luaVm.FromValue(rets[0], &cfg); err != nil {
+		t.Fatalf("FromValue: %v", err)
+	}
+	if cfg.Name != "prod" || len(cfg.Servers) != 2 || cfg.Servers[1] != (server{"b", 8080}) {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+	if cfg.Weights["x"] != 1 || cfg.Weights["y"] != 0.5 {
+		t.Fatalf("Weights = %v", cfg.Weights)
+	}
+	if cfg.Opt == nil || *cfg.Opt != 3 {
+		t.Fatalf("Opt = %v, want 3", cfg.Opt)
+	}
+	if cfg.Skipped != "" {
+		t.Fatalf("Skipped = %q, want empty", cfg.Skipped)
+	}
+	// A table with both array and string keys decodes into map[any]any
+	extra, ok := cfg.Extra.(map[any]any)
+	if !ok || extra["nested"] != "x" || len(extra) != 3 {
+		t.Fatalf("Extra = %#v", cfg.Extra)
+	}
+}
+
+func TestFromValueInterface(t *testing.T) {
+	luaVm := newVm(t)
+
+	rets := exec(t, luaVm, `return { "a", "b" }, { k = "v" }, "s", true`)
+	if got := decode[any](t, luaVm, rets[0]); len(got.([]any)) != 2 || got.([]any)[1] != "b" {
+		t.Errorf("array = %#v, want []any{a, b}", got)
+	}
+	if got := decode[any](t, luaVm, rets[1]); got.(map[string]any)["k"] != "v" {
+		t.Errorf("map = %#v, want map[string]any{k: v}", got)
+	}
+	if got := decode[any](t, luaVm, rets[2]); got != "s" {
+		t.Errorf("string = %#v, want s", got)
+	}
+	if got := decode[any](t, luaVm, rets[3]); got != true {
+		t.Errorf("bool = %#v, want true", got)
+	}
+}
+
+func TestFromValueErrors(t *testing.T) {
+	luaVm := newVm(t)
+
+	for _, tc := range []struct {
+		code string
+		want string
+	}{
+		{`return { servers = { { port = 1 }, { port = "x" } } }`, "servers[2].port: expected integer, got string"},
+		{`return { servers = { { port = 70000 } } }`, "servers[1].port: integer 70000 overflows uint16"},
+		{`return { servers = { { port = 1.5 } } }`, "servers[1].port: number 1.5 is not an integer"},
+		{`return { servers = { [1] = {}, [3] = {} } }`, "servers: expected array-like table, got table with non-sequential keys"},
+		{`return { Name = true }`, "Name: expected string, got boolean"},
+	} {
+		rets := exec(t, luaVm, tc.code)
+		var cfg config
+		err := luaVm.FromValue(rets[0], &cfg)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%s: err = %v, want %q", tc.code, err, tc.want)
+		}
+	}
+
+	var n int
+	if err := luaVm.FromValue(vm.NewValueInteger(1), n); err == nil {
+		t.Error("FromValue into a non-pointer succeeded")
+	}
+}
+
+func TestFromValueCycle(t *testing.T) {
+	luaVm := newVm(t)
+
+	rets := exec(t, luaVm, `local t = {} t.self = t return t`)
+	var anyVal any
+	if err := luaVm.FromValue(rets[0], &anyVal); err == nil || err.Error() != "self: cyclic table" {
+		t.Errorf("FromValue into any: err = %v, want %q", err, "self: cyclic table")
+	}
+
+	type node struct {
+		Self *node `lua:"self"`
+	}
+	var n node
+	if err := luaVm.FromValue(rets[0], &n); err == nil || err.Error() != "self: cyclic table" {
+		t.Errorf("FromValue into struct: err = %v, want %q", err, "self: cyclic table")
+	}
+
+	// Tables referenced more than once without a cycle decode fine
+	rets = exec(t, luaVm, `local t = { 1 } return { a = t, b = t }`)
+	m := decode[map[string][]int](t, luaVm, rets[0])
+	if len(m["a"]) != 1 || len(m["b"]) != 1 {
+		t.Errorf("decoded shared table = %v", m)
+	}
+}
@@ -10,6 +10,7 @@ import (
 )
 
 var userdataTab = objectTab{
+	name: "LuaUserData",
 	dtor: func(ptr *C.void) {
 		C.luago_free_userdata((*C.struct_LuaUserData)(unsafe.Pointer(ptr)))
 	},
@@ -33,6 +33,40 @@ const (
 	LuaValueCustom_GoString LuaValueType = 14
 )
 
+// String returns the name of the type as used by Luau's typeof
+func (t LuaValueType) String() string {
+	switch t {
+	case LuaValueNil:
+		return "nil"
+	case LuaValueBoolean:
+		return "boolean"
+	case LuaValueLightUserData:
+		return "lightuserdata"
+	case LuaValueInteger:
+		return "integer"
+	case LuaValueNumber:
+		return "number"
+	case LuaValueVector:
+		return "vector"
+	case LuaValueString, LuaValueCustom_GoString:
+		return "string"
+	case LuaValueTable:
+		return "table"
+	case LuaValueFunction:
+		return "function"
+	case LuaValueThread:
+		return "thread"
+	case LuaValueUserData:
+		return "userdata"
+	case LuaValueBuffer:
+		return "buffer"
+	case LuaValueError:
+		return "error"
+	default:
+		return "other"
+	}
+}
+
 type Value interface {
 	Type() LuaValueType
 	Close()
@@ -245,18 +279,25 @@ func (v *ValueFunction) needsClone() bool {
 	return true // Function needs to be cloned to be safely passed to rust
 }
 
+// ValueThread represents a Lua thread (coroutine) value.
 type ValueThread struct {
-	value *C.void // TODO
+	value *LuaThread
 }
 
+func (v *ValueThread) Value() *LuaThread {
+	return v.value
+}
 func (v *ValueThread) Type() LuaValueType {
 	return LuaValueThread
 }
 func (v *ValueThread) Close() {
-	// TODO: Implement thread
+	v.value.Close()
 }
 func (v *ValueThread) object() *object {
-	return nil // Thread has no underlying object
+	if v.value == nil {
+		return nil // Thread has no underlying object if nil
+	}
+	return v.value.object
 }
 func (v *ValueThread) needsClone() bool {
 	return true // Thread needs to be cloned to be safely passed to rust
@@ -279,18 +320,25 @@ func (v *ValueUserData) needsClone() bool {
 	return true // UserData needs to be cloned to be safely passed to rust
 }
 
+// ValueBuffer represents a Luau buffer value.
 type ValueBuffer struct {
-	value *C.void // TODO
+	value *LuaBuffer
 }
 
+func (v *ValueBuffer) Value() *LuaBuffer {
+	return v.value
+}
 func (v *ValueBuffer) Type() LuaValueType {
 	return LuaValueBuffer
 }
 func (v *ValueBuffer) Close() {
-	// TODO: Implement buffer
+	v.value.Close()
 }
 func (v *ValueBuffer) object() *object {
-	return nil // Buffer has no underlying object
+	if v.value == nil {
+		return nil // Buffer has no underlying object if nil
+	}
+	return v.value.object
 }
 func (v *ValueBuffer) needsClone() bool {
 	return true // Buffer needs to be cloned to be safely passed to rust
@@ -386,35 +434,37 @@ func (l *GoLuaVmWrapper) valueFromC(item C.struct_GoLuaValue) Value {
 	case C.LuaValueTypeString:
 		ptrToPtr := (**C.struct_LuaString)(unsafe.Pointer(&item.data))
 		strPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		str := &LuaString{object: newObject(strPtr, stringTab)}
+		str := &LuaString{object: newObject(strPtr, stringTab, l.vmState())}
 		return &ValueString{value: str}
 	case C.LuaValueTypeTable:
 		ptrToPtr := (**C.struct_LuaTable)(unsafe.Pointer(&item.data))
 		tabPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		tab := &LuaTable{object: newObject(tabPtr, tableTab), lua: l}
+		tab := &LuaTable{object: newObject(tabPtr, tableTab, l.vmState()), lua: l.owner()}
 		return &ValueTable{value: tab}
 	case C.LuaValueTypeFunction:
 		ptrToPtr := (**C.struct_LuaFunction)(unsafe.Pointer(&item.data))
 		funcPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		funct := &LuaFunction{object: newObject(funcPtr, functionTab)}
+		funct := &LuaFunction{object: newObject(funcPtr, functionTab, l.vmState()), lua: l.owner()}
 		return &ValueFunction{value: funct}
 	case C.LuaValueTypeThread:
-		threadPtrPtr := (**C.void)(unsafe.Pointer(&item.data))
-		threadPtr := *threadPtrPtr
-		return &ValueThread{value: threadPtr} // TODO: Support threads
+		ptrToPtr := (**C.struct_LuaThread)(unsafe.Pointer(&item.data))
+		threadPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
+		thread := &LuaThread{object: newObject(threadPtr, threadTab, l.vmState()), lua: l.owner()}
+		return &ValueThread{value: thread}
 	case C.LuaValueTypeUserData:
 		ptrToPtr := (**C.struct_LuaUserData)(unsafe.Pointer(&item.data))
 		udPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		udt := &LuaUserData{object: newObject(udPtr, userdataTab)}
+		udt := &LuaUserData{object: newObject(udPtr, userdataTab, l.vmState()), lua: l.owner()}
 		return &ValueUserData{value: udt}
 	case C.LuaValueTypeBuffer:
-		bufferPtrPtr := (**C.void)(unsafe.Pointer(&item.data))
-		bufferPtr := *bufferPtrPtr
-		return &ValueBuffer{value: bufferPtr} // TODO: Support buffers
+		ptrToPtr := (**C.struct_LuaBuffer)(unsafe.Pointer(&item.data))
+		bufferPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
+		buffer := &LuaBuffer{object: newObject(bufferPtr, bufferTab, l.vmState()), lua: l.owner()}
+		return &ValueBuffer{value: buffer}
 	case C.LuaValueTypeError:
 		ptrToPtr := (**C.struct_ErrorVariant)(unsafe.Pointer(&item.data))
 		strPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		str := &ErrorVariant{object: newObject(strPtr, errorVariantTab)}
+		str := &ErrorVariant{object: newObject(strPtr, errorVariantTab, l.vmState())}
 		return &ValueError{value: str}
 	case C.LuaValueTypeOther:
 		// Currently, always nil
@@ -476,7 +526,7 @@ func (l *GoLuaVmWrapper) _directValueToC(value Value) (C.struct_GoLuaValue, erro
 		if err != nil {
 			return cVal, errors.New("cannot convert closed LuaTable to C value")
 		}
-		cVal.tag = C.LuaValueTypeString
+		cVal.tag = C.LuaValueTypeTable
 		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(ptr)
 	case LuaValueFunction:
 		funcVal := value.(*ValueFunction)
@@ -488,11 +538,15 @@ func (l *GoLuaVmWrapper) _directValueToC(value Value) (C.struct_GoLuaValue, erro
 		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(ptr)
 	case LuaValueThread:
 		threadVal := value.(*ValueThread)
-		if threadVal.value == nil {
-			return cVal, errors.New("cannot convert nil LuaThread to C value")
+		if threadVal.value == nil || threadVal.value.object == nil {
+			return cVal, errClosedObject()
+		}
+		ptr, err := threadVal.value.object.PointerNoLock()
+		if err != nil {
+			return cVal, errors.New("cannot convert closed LuaThread to C value")
 		}
 		cVal.tag = C.LuaValueTypeThread
-		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(threadVal.value)
+		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(ptr)
 	case LuaValueUserData:
 		udVal := value.(*ValueUserData)
 		ptr, err := udVal.value.object.PointerNoLock()
@@ -503,11 +557,15 @@ func (l *GoLuaVmWrapper) _directValueToC(value Value) (C.struct_GoLuaValue, erro
 		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(ptr)
 	case LuaValueBuffer:
 		bufferVal := value.(*ValueBuffer)
-		if bufferVal.value == nil {
-			return cVal, errors.New("cannot convert nil LuaBuffer to C value")
+		if bufferVal.value == nil || bufferVal.value.object == nil {
+			return cVal, errClosedObject()
+		}
+		ptr, err := bufferVal.value.object.PointerNoLock()
+		if err != nil {
+			return cVal, errors.New("cannot convert closed LuaBuffer to C value")
 		}
 		cVal.tag = C.LuaValueTypeBuffer
-		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(bufferVal.value)
+		*(*unsafe.Pointer)(unsafe.Pointer(&cVal.data)) = unsafe.Pointer(ptr)
 	case LuaValueError:
 		errVal := value.(*ValueError)
 		ptr, err := errVal.value.object.PointerNoLock()
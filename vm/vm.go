@@ -5,19 +5,62 @@ package vm
 */
 import "C"
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 var luaVmTab = objectTab{
+	name: "GoLuaVmWrapper",
 	dtor: func(ptr *C.void) {
 		C.freeluavm((*C.struct_LuaVmWrapper)(unsafe.Pointer(ptr)))
 	},
 }
 
+// vmState is the state shared between a Lua VM and every
+// GoLuaVmWrapper derived from it (such as the callback VMs
+// passed to a FunctionFn)
+type vmState struct {
+	opts VmOptions
+
+	// Instruction budget (in interrupt ticks) per call, 0 if unlimited
+	budget atomic.Uint64
+	// Context of the innermost CallContext call, nil if none
+	callCtx atomic.Pointer[context.Context]
+	// The memory limit last set by SetMemoryLimit
+	memoryLimit atomic.Int64
+
+	// The executor owning the Lua VM, started on first use of Do/Go
+	executorOnce sync.Once
+	executor     *executor
+
+	// Application data set by SetAppData
+	appDataMu sync.RWMutex
+	appData   any
+
+	// Live handles of the Lua VM, nil unless VmOptions.TrackHandles is set
+	handles *handleTracker
+
+	// Custom Go <-> Luau converters registered with RegisterConverter
+	convertersMu sync.RWMutex
+	converters   map[reflect.Type]converter
+
+	// Panic of a Go callback to re-panic with, see PanicRepanic
+	pendingPanic atomic.Pointer[CallbackPanic]
+}
+
 // Internal VM wrapper
 type GoLuaVmWrapper struct {
-	obj *object
+	obj   *object
+	state *vmState
+	// Whether this wrapper owns the Lua VM (as opposed to
+	// being a callback VM derived from it)
+	root bool
+	// The wrapper owning the Lua VM for callback VMs (nil for the owner itself)
+	ownerVm *GoLuaVmWrapper
 }
 
 func (l *GoLuaVmWrapper) lua() (*C.struct_LuaVmWrapper, error) {
@@ -62,9 +105,56 @@ func (l *GoLuaVmWrapper) SetMemoryLimit(limit int) error {
 		err := moveErrorToGoError(res.error)
 		return err
 	}
+	l.state.memoryLimit.Store(int64(limit))
 	return nil
 }
 
+// Globals returns the global table of the Lua VM.
+//
+// Returns nil if the Lua VM is closed.
+func (l *GoLuaVmWrapper) Globals() *LuaTable {
+	l.obj.RLock()
+	defer l.obj.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil
+	}
+
+	ptr := C.luavm_globals(lua)
+	if ptr == nil {
+		return nil
+	}
+	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(ptr)), tableTab, l.state), lua: l.owner()}
+}
+
+// SetGlobal sets a global variable in the global table of the Lua VM.
+//
+// Unlike passing a table as ChunkOpts.Env, this keeps the standard library
+// available to scripts.
+func (l *GoLuaVmWrapper) SetGlobal(name string, v Value) error {
+	globals := l.Globals()
+	if globals == nil {
+		return errClosedObject()
+	}
+	defer globals.Close()
+
+	return globals.Set(GoString(name), v)
+}
+
+// GetGlobal returns the value of a global variable in the global table of the Lua VM.
+//
+// If the global does not exist, it returns LuaValue of nil
+func (l *GoLuaVmWrapper) GetGlobal(name string) (Value, error) {
+	globals := l.Globals()
+	if globals == nil {
+		return &ValueNil{}, errClosedObject()
+	}
+	defer globals.Close()
+
+	return globals.Get(GoString(name))
+}
+
 // CreateString creates a Lua string from a Go string.
 func (l *GoLuaVmWrapper) CreateString(s string) (*LuaString, error) {
 	return l.createString([]byte(s))
@@ -91,14 +181,14 @@ func (l *GoLuaVmWrapper) createString(s []byte) (*LuaString, error) {
 		if res.error != nil {
 			return nil, moveErrorToGoError(res.error)
 		}
-		return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab)}, nil
+		return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab, l.state)}, nil
 	}
 
 	res := C.luago_create_string(lua, (*C.char)(unsafe.Pointer(&s[0])), C.size_t(len(s)))
 	if res.error != nil {
 		return nil, moveErrorToGoError(res.error)
 	}
-	return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab)}, nil
+	return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab, l.state)}, nil
 }
 
 // Create string as pointer (without any finalizer)
@@ -142,7 +232,7 @@ func (l *GoLuaVmWrapper) CreateTable() (*LuaTable, error) {
 		err := moveErrorToGoError(res.error)
 		return nil, err
 	}
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
+	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab, l.state), lua: l.owner()}, nil
 }
 
 // CreateTableWithCapacity creates a new Lua table with specified capacity for array and record parts.
@@ -161,7 +251,7 @@ func (l *GoLuaVmWrapper) CreateTableWithCapacity(narr, nrec int) (*LuaTable, err
 		err := moveErrorToGoError(res.error)
 		return nil, err
 	}
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
+	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab, l.state), lua: l.owner()}, nil
 }
 
 // CreateErrorVariant creates a new ErrorVariant from a byte slice.
@@ -169,19 +259,59 @@ func CreateErrorVariant(s []byte) *ErrorVariant {
 	if len(s) == 0 {
 		// Passing nil to luago_create_string creates an empty string.
 		res := C.luago_error_new((*C.char)(nil), C.size_t(len(s)))
-		return &ErrorVariant{object: newObject((*C.void)(unsafe.Pointer(res)), errorVariantTab)}
+		return &ErrorVariant{object: newObject((*C.void)(unsafe.Pointer(res)), errorVariantTab, nil)}
 	}
 
 	res := C.luago_error_new((*C.char)(unsafe.Pointer(&s[0])), C.size_t(len(s)))
-	return &ErrorVariant{object: newObject((*C.void)(unsafe.Pointer(res)), errorVariantTab)}
+	return &ErrorVariant{object: newObject((*C.void)(unsafe.Pointer(res)), errorVariantTab, nil)}
+}
+
+// newErrorVariantC creates a new C ErrorVariant holding msg
+func newErrorVariantC(msg string) *C.struct_ErrorVariant {
+	if len(msg) == 0 {
+		return C.luago_error_new((*C.char)(nil), 0)
+	}
+	msgBytes := []byte(msg)
+	return C.luago_error_new((*C.char)(unsafe.Pointer(&msgBytes[0])), C.size_t(len(msgBytes)))
 }
 
 type FunctionFn = func(funcVm *GoLuaVmWrapper, args []Value) ([]Value, error)
 
 // CreateFunction creates a new Function
 //
-// Note that funcVm will only be open until the callback function returns
+// Note that funcVm will only be open until the callback function returns.
+// Objects created through funcVm (e.g. by funcVm.LoadChunk) belong to the
+// Lua VM itself, so they can still be used after that.
+//
+// An error returned by callback is raised in Luau as a userdata (so that
+// the Go error survives pcall and error, see LuaError.Unwrap). The
+// userdata behaves like its message for tostring, .. and string methods
+// (e.g. err:match("...")) and has a message field, but type(err) is
+// "userdata". To raise it this way, Luau calls callback through a
+// wrapper closure, adding one frame to every call.
+//
+// Panics in callback are recovered and handled according to
+// VmOptions.PanicPolicy.
 func (l *GoLuaVmWrapper) CreateFunction(callback FunctionFn) (*LuaFunction, error) {
+	return l.CreateFunctionWithOnDrop(callback, nil)
+}
+
+// CreateFunctionWithOnDrop creates a new Function like CreateFunction,
+// calling onDrop once Luau no longer references the function (and the
+// callback will never be called again).
+func (l *GoLuaVmWrapper) CreateFunctionWithOnDrop(callback FunctionFn, onDrop func()) (*LuaFunction, error) {
+	return l.createFunction(func(funcVm *GoLuaVmWrapper, args []Value) ([]Value, []Value, error) {
+		values, err := callback(funcVm, args)
+		return values, nil, err
+	}, onDrop)
+}
+
+// ownedFunctionFn is like FunctionFn, but also returns the values that were
+// created by the callback and must be closed once they have been passed to Lua
+type ownedFunctionFn = func(funcVm *GoLuaVmWrapper, args []Value) (values, owned []Value, err error)
+
+// createFunction implements CreateFunctionWithOnDrop for an ownedFunctionFn
+func (l *GoLuaVmWrapper) createFunction(callback ownedFunctionFn, onDrop func()) (*LuaFunction, error) {
 	l.obj.RLock()
 	defer l.obj.RUnlock()
 
@@ -201,12 +331,17 @@ func (l *GoLuaVmWrapper) CreateFunction(callback FunctionFn) (*LuaFunction, erro
 				// Deallocate any existing error
 				if cval.error != nil {
 					C.luago_error_free(cval.error)
+					cval.error = nil
 				}
 
-				// Replace
-				errBytes := []byte(fmt.Sprintf("panic in CreateFunction callback: %v", r))
-				errv := C.luago_error_new((*C.char)(unsafe.Pointer(&errBytes[0])), C.size_t(len(errBytes)))
-				cval.error = errv // Rust side will deallocate it for us
+				// Replace (see VmOptions.PanicPolicy)
+				p := newCallbackPanic(r)
+				err := l.state.handleCallbackPanic(p)
+				if err == nil {
+					return // Handled by the PanicHook
+				}
+				cval.error = newErrorVariantC(err.Error()) // Rust side will deallocate it for us
+				cval.go_error = newGoErrorCallback(err, p.pcs).ToC()
 			}
 		}()
 
@@ -215,28 +350,36 @@ func (l *GoLuaVmWrapper) CreateFunction(callback FunctionFn) (*LuaFunction, erro
 		mw := &luaMultiValue{ptr: cval.args, lua: l}
 		args := mw.take()
 
-		callbackVm := &GoLuaVmWrapper{obj: newObject((*C.void)(unsafe.Pointer(cval.lua)), luaVmTab)}
-		values, err := callback(callbackVm, args)
+		callbackVm := &GoLuaVmWrapper{obj: newObject((*C.void)(unsafe.Pointer(cval.lua)), luaVmTab, l.state), state: l.state, ownerVm: l.owner()}
+		values, owned, err := callback(callbackVm, args)
 		defer callbackVm.Close() // Free the memory associated with the callback VM
+		defer func() {
+			// The values have been copied into the multivalue by now
+			for _, v := range owned {
+				v.Close()
+			}
+		}()
 
 		if err != nil {
-			errBytes := []byte(err.Error())
-			errv := C.luago_error_new((*C.char)(unsafe.Pointer(&errBytes[0])), C.size_t(len(errBytes)))
-			cval.error = errv // Rust side will deallocate it for us
+			cval.error = newErrorVariantC(err.Error()) // Rust side will deallocate it for us
+			// Pass the error itself along so that it can be returned
+			// (as the cause of the LuaError) once it reaches Go again
+			cval.go_error = newGoErrorCallback(err, callers(0)).ToC()
 			return
 		}
 
 		outMw, err := l.multiValueFromValues(values)
 		if err != nil {
-			errBytes := []byte(err.Error())
-			errv := C.luago_error_new((*C.char)(unsafe.Pointer(&errBytes[0])), C.size_t(len(errBytes)))
-			cval.error = errv // Rust side will deallocate it for us
+			cval.error = newErrorVariantC(err.Error()) // Rust side will deallocate it for us
 			return
 		}
 
 		cval.values = outMw.ptr // Rust will deallocate values as well
 	}, func() {
-		fmt.Println("function callback is being dropped")
+		if onDrop != nil {
+			onDrop()
+		}
+		l.state.logEvent(EventCallbackDropped, "LuaFunction")
 	})
 
 	res := C.luago_create_function(lua, cbWrapper.ToC())
@@ -245,7 +388,7 @@ func (l *GoLuaVmWrapper) CreateFunction(callback FunctionFn) (*LuaFunction, erro
 		return nil, err
 	}
 
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab, l.state), lua: l.owner()}, nil
 }
 
 // LoadChunk loads a Lua chunk from the given options.
@@ -292,11 +435,37 @@ func (l *GoLuaVmWrapper) LoadChunk(opts ChunkOpts) (*LuaFunction, error) {
 		err := moveErrorToGoError(res.error)
 		return nil, err
 	}
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab, l.state), lua: l.owner()}, nil
+}
+
+// ExecChunk loads a Lua chunk from the given options and calls it
+// with no arguments, returning the values returned by the chunk.
+func (l *GoLuaVmWrapper) ExecChunk(opts ChunkOpts) ([]Value, error) {
+	return l.ExecChunkContext(context.Background(), opts)
+}
+
+// ExecChunkContext is like ExecChunk but stops the chunk once ctx is
+// cancelled or its deadline is exceeded.
+//
+// See LuaFunction.CallContext for more information.
+func (l *GoLuaVmWrapper) ExecChunkContext(ctx context.Context, opts ChunkOpts) ([]Value, error) {
+	fn, err := l.LoadChunk(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer fn.Close()
+
+	return fn.CallContext(ctx, nil)
 }
 
 // CreateUserData creates a LuaUserData with associated data and a metatable.
 func (l *GoLuaVmWrapper) CreateUserData(associatedData any, mt *LuaTable) (*LuaUserData, error) {
+	return l.CreateUserDataWithOnDrop(associatedData, mt, nil)
+}
+
+// CreateUserDataWithOnDrop creates a LuaUserData like CreateUserData,
+// calling onDrop with the associated data once Luau drops the userdata.
+func (l *GoLuaVmWrapper) CreateUserDataWithOnDrop(associatedData any, mt *LuaTable, onDrop func(data any)) (*LuaUserData, error) {
 	if mt == nil {
 		return nil, fmt.Errorf("metatable cannot be nil")
 	}
@@ -317,7 +486,10 @@ func (l *GoLuaVmWrapper) CreateUserData(associatedData any, mt *LuaTable) (*LuaU
 	}
 
 	dynData := newDynamicData(associatedData, func() {
-		fmt.Println("dynamic data is being dropped")
+		if onDrop != nil {
+			onDrop(associatedData)
+		}
+		l.state.logEvent(EventUserDataDropped, "LuaUserData")
 	})
 	cDynData := dynData.ToC()
 	res := C.luago_create_userdata(lua, cDynData, (*C.struct_LuaTable)(unsafe.Pointer(mtPtr)))
@@ -326,8 +498,8 @@ func (l *GoLuaVmWrapper) CreateUserData(associatedData any, mt *LuaTable) (*LuaU
 		return nil, err
 	}
 	return &LuaUserData{
-		lua:    l,
-		object: newObject((*C.void)(unsafe.Pointer(res.value)), userdataTab),
+		lua:    l.owner(),
+		object: newObject((*C.void)(unsafe.Pointer(res.value)), userdataTab, l.state),
 	}, nil
 }
 
@@ -353,15 +525,75 @@ func (l *GoLuaVmWrapper) Close() {
 		return // Nothing to close
 	}
 
+	if l.root {
+		// Prevent the executor from being started after the Lua VM is closed
+		// and let already submitted work finish first
+		l.state.executorOnce.Do(func() {})
+		if l.state.executor != nil && l.state.executor.stop() {
+			// Closed from executor work, which may be in a call holding
+			// the Lua VM, so the executor closes it once the work returns
+			return
+		}
+	}
+
 	// Close the Lua VM object
 	l.obj.Close()
 }
 
+// Options returns the options the Lua VM was created with.
+func (l *GoLuaVmWrapper) Options() VmOptions {
+	return l.state.opts
+}
+
+// StdLibs returns the standard libraries loaded into the Lua VM.
+func (l *GoLuaVmWrapper) StdLibs() StdLib {
+	return l.state.opts.StdLibs
+}
+
+// owner returns the wrapper owning the Lua VM. Objects created through a
+// callback VM belong to it (instead of the callback VM) so that they
+// can still be used once the callback returns.
+func (l *GoLuaVmWrapper) owner() *GoLuaVmWrapper {
+	if l.ownerVm == nil {
+		return l
+	}
+	return l.ownerVm
+}
+
+// vmState returns the state of the Lua VM, or nil if l is nil
+func (l *GoLuaVmWrapper) vmState() *vmState {
+	if l == nil {
+		return nil
+	}
+	return l.state
+}
+
+// CreateLuaVm creates a new Lua VM with all safe standard libraries loaded.
 func CreateLuaVm() (*GoLuaVmWrapper, error) {
-	ptr := C.newluavm()
-	if ptr == nil {
-		return nil, fmt.Errorf("failed to create Lua VM")
+	return CreateLuaVmWithOptions(VmOptions{StdLibs: StdLibAllSafe})
+}
+
+// CreateLuaVmWithOptions creates a new Lua VM with the given options.
+func CreateLuaVmWithOptions(opts VmOptions) (*GoLuaVmWrapper, error) {
+	state := &vmState{opts: opts}
+	if opts.TrackHandles {
+		state.handles = newHandleTracker()
+	}
+
+	onClose := newGoCallback(func(val unsafe.Pointer) {
+		state.logEvent(EventVmClosed, "")
+	}, nil)
+
+	cOpts := opts.toC()
+	cOpts.on_close = onClose.ToC()
+	res := C.newluavm_with_options(cOpts)
+	if res.error != nil {
+		return nil, moveErrorToGoError(res.error)
+	}
+	vm := &GoLuaVmWrapper{
+		obj:   newObject((*C.void)(unsafe.Pointer(res.value)), luaVmTab, state),
+		state: state,
+		root:  true,
 	}
-	vm := &GoLuaVmWrapper{obj: newObject((*C.void)(unsafe.Pointer(ptr)), luaVmTab)}
 	return vm, nil
 }
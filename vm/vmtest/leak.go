@@ -0,0 +1,50 @@
+// Package vmtest provides helpers for testing code using gluau.
+package vmtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// CheckLeaks fails tb if any handle of luaVm created after CheckLeaks is
+// called is still open once tb (and any cleanup registered after CheckLeaks)
+// has finished.
+//
+// luaVm must have been created with VmOptions.TrackHandles, otherwise
+// CheckLeaks fails tb immediately.
+//
+// Usage:
+//
+//	luaVm, _ := vm.CreateLuaVmWithOptions(vm.VmOptions{StdLibs: vm.StdLibAllSafe, TrackHandles: true})
+//	defer luaVm.Close()
+//	vmtest.CheckLeaks(t, luaVm)
+func CheckLeaks(tb testing.TB, luaVm *vm.GoLuaVmWrapper) {
+	tb.Helper()
+	if !luaVm.Options().TrackHandles {
+		tb.Fatal("vmtest.CheckLeaks: Lua VM was not created with VmOptions.TrackHandles")
+		return
+	}
+
+	start := luaVm.LastHandleID()
+	tb.Cleanup(func() {
+		var leaked []vm.HandleInfo
+		for _, h := range luaVm.LiveHandles() {
+			if h.ID > start {
+				leaked = append(leaked, h)
+			}
+		}
+		if len(leaked) == 0 {
+			return
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d handle(s) leaked:\n", len(leaked))
+		for _, h := range leaked {
+			fmt.Fprintf(&sb, "\n%s #%d created at:\n%s", h.Type, h.ID, h.Stack)
+		}
+		tb.Error(sb.String())
+	})
+}
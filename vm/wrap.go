@@ -0,0 +1,262 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	luaVmType   = reflect.TypeOf((*GoLuaVmWrapper)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// WrapFunc creates a Luau function from an arbitrary Go function,
+// converting arguments and return values using reflection.
+//
+//	fn, err := vm.WrapFunc(func(name string, n int) (string, error) {
+//		return strings.Repeat(name, n), nil
+//	})
+//
+// Arguments are decoded using the same rules as FromValue and failures are
+// reported as "bad argument" errors in the manner of the Luau standard
+// library (e.g. "bad argument #2 to 'Repeat' (number expected, got nil)"
+// for strings.Repeat). The function is named in the errors if it is a named
+// Go function or method, errors of anonymous functions leave the name out.
+// Missing (or nil) arguments are only accepted for pointer and interface
+// parameters (and types with a custom conversion, see LuaUnmarshaler).
+// Extra arguments are ignored unless the function is variadic, in which
+// case they are decoded into the variadic parameter.
+//
+// The function may take a context.Context and/or a *GoLuaVmWrapper as its
+// leading parameters, which are passed the context of the calling Luau
+// code (see GoLuaVmWrapper.Context) and the callback VM respectively.
+//
+// Return values are converted using the same rules as ToValue. If the last
+// return value is an error, a non-nil error is raised as a Luau error.
+func (l *GoLuaVmWrapper) WrapFunc(fn any) (*LuaFunction, error) {
+	w, err := newWrappedFunc(fn)
+	if err != nil {
+		return nil, err
+	}
+	return l.createFunction(w.call, nil)
+}
+
+// wrappedFunc is a Go function wrapped by WrapFunc
+type wrappedFunc struct {
+	fn   reflect.Value
+	name string
+
+	// Whether the function takes a context.Context/*GoLuaVmWrapper,
+	// and their parameter index (or -1)
+	ctxIdx int
+	vmIdx  int
+	// Number of leading special parameters
+	nspecial int
+	// Whether the last return value is an error
+	returnsErr bool
+}
+
+func newWrappedFunc(fn any) (*wrappedFunc, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T: not a function", fn)
+	}
+
+	ft := fv.Type()
+	w := &wrappedFunc{fn: fv, name: funcName(fv), ctxIdx: -1, vmIdx: -1}
+	for i := 0; i < ft.NumIn() && i < 2; i++ {
+		in := ft.In(i)
+		if in == contextType && w.ctxIdx == -1 {
+			w.ctxIdx = i
+		} else if in == luaVmType && w.vmIdx == -1 {
+			w.vmIdx = i
+		} else {
+			break
+		}
+		w.nspecial++
+	}
+
+	nout := ft.NumOut()
+	if nout > 0 && ft.Out(nout-1) == errorType {
+		w.returnsErr = true
+	}
+	return w, nil
+}
+
+// funcName returns the name of the Go function fv without its package
+// (and receiver), or "" if it is an anonymous function
+func funcName(fv reflect.Value) string {
+	f := runtime.FuncForPC(fv.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name() // e.g. example.com/pkg.(*T).Method-fm or example.com/pkg.Outer.func1
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm") // Method values
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i] // Instantiated generic functions
+	}
+	parts := strings.Split(name, ".")
+	name = parts[len(parts)-1]
+	for _, part := range parts[1:] {
+		// Anonymous functions are named funcN (with nested ones numbered N.M)
+		if strings.Trim(strings.TrimPrefix(part, "func"), "0123456789") == "" {
+			return ""
+		}
+	}
+	return name
+}
+
+// argTypeName returns the name of the Luau type expected for a parameter
+// of type typ (for error messages), or "" if there is no single such type
+func argTypeName(typ reflect.Type) string {
+	switch typ {
+	case luaStringType:
+		return "string"
+	case luaTableType:
+		return "table"
+	case luaFunctionType:
+		return "function"
+	case luaUserDataType:
+		return "userdata"
+	case luaThreadType:
+		return "thread"
+	case luaBufferType:
+		return "buffer"
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return argTypeName(typ.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "table"
+	case reflect.Array, reflect.Map, reflect.Struct:
+		return "table"
+	}
+	return ""
+}
+
+// hasCustomDecoding returns true if values of type typ are decoded using
+// a registered converter or LuaUnmarshaler (see unmarshalCustom)
+func (l *GoLuaVmWrapper) hasCustomDecoding(typ reflect.Type) bool {
+	if conv, ok := l.vmState().converter(typ); ok && conv.fromValue != nil {
+		return true
+	}
+	return reflect.PointerTo(typ).Implements(unmarshalerType)
+}
+
+func (w *wrappedFunc) call(funcVm *GoLuaVmWrapper, args []Value) (rets, owned []Value, err error) {
+	ft := w.fn.Type()
+	nin := ft.NumIn()
+	in := make([]reflect.Value, 0, nin)
+
+	// Special parameters
+	for i := 0; i < w.nspecial; i++ {
+		switch i {
+		case w.ctxIdx:
+			in = append(in, reflect.ValueOf(funcVm.Context()))
+		case w.vmIdx:
+			in = append(in, reflect.ValueOf(funcVm))
+		}
+	}
+
+	d := &decoder{lua: funcVm}
+	a := NewArgs(w.name, args)
+	decodeArg := func(n int, typ reflect.Type) (reflect.Value, error) {
+		arg := reflect.New(typ).Elem()
+		v := a.Get(n)
+		elem := typ
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		custom := funcVm.hasCustomDecoding(typ) || funcVm.hasCustomDecoding(elem)
+		expected := argTypeName(typ)
+		if expected == "" {
+			expected = typ.String()
+		}
+		if v.Type() == LuaValueNil && !custom && typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+			return arg, a.typeError(n, expected)
+		}
+		if _, err := d.decode(v, arg, ""); err != nil {
+			if !custom && luauTypeName(v) != expected {
+				return arg, a.typeError(n, expected)
+			}
+			// The value has the right type but cannot be decoded
+			// (e.g. a number out of range or a malformed table)
+			return arg, a.ArgError(n, err.Error())
+		}
+		return arg, nil
+	}
+
+	// Regular parameters
+	nregular := nin - w.nspecial
+	if ft.IsVariadic() {
+		nregular--
+	}
+	for i := 0; i < nregular; i++ {
+		arg, err := decodeArg(i+1, ft.In(w.nspecial+i))
+		if err != nil {
+			return nil, nil, err
+		}
+		in = append(in, arg)
+	}
+
+	var out []reflect.Value
+	if ft.IsVariadic() {
+		elemType := ft.In(nin - 1).Elem()
+		nvariadic := len(args) - nregular
+		if nvariadic < 0 {
+			nvariadic = 0
+		}
+		variadic := reflect.MakeSlice(ft.In(nin-1), nvariadic, nvariadic)
+		for i := 0; i < nvariadic; i++ {
+			arg, err := decodeArg(nregular+i+1, elemType)
+			if err != nil {
+				return nil, nil, err
+			}
+			variadic.Index(i).Set(arg)
+		}
+		in = append(in, variadic)
+		out = w.fn.CallSlice(in)
+	} else {
+		out = w.fn.Call(in)
+	}
+
+	if w.returnsErr {
+		if errv := out[len(out)-1]; !errv.IsNil() {
+			return nil, nil, errv.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+
+	rets = make([]Value, 0, len(out))
+	for i, o := range out {
+		e := &encoder{lua: funcVm, visiting: map[visitKey]struct{}{}}
+		val, isOwned, err := e.encode(o, "")
+		if err != nil {
+			return nil, owned, fmt.Errorf("cannot convert return value #%d: %w", i+1, err)
+		}
+		if isOwned {
+			owned = append(owned, val)
+		}
+		rets = append(rets, val)
+	}
+	return rets, owned, nil
+}
@@ -0,0 +1,107 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gluau/gluau/vm"
+)
+
+// wrap wraps fn as the global name
+func wrap(t *testing.T, luaVm *vm.GoLuaVmWrapper, name string, fn any) {
+	t.Helper()
+	f, err := luaVm.WrapFunc(fn)
+	if err != nil {
+		t.Fatalf("WrapFunc: %v", err)
+	}
+	defer f.Close()
+	if err := luaVm.SetGlobal(name, f.ToValue()); err != nil {
+		t.Fatalf("SetGlobal: %v", err)
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	luaVm := newVm(t)
+	wrap(t, luaVm, "rep", func(s string, n int) string { return strings.Repeat(s, n) })
+	wrap(t, luaVm, "sum", func(first int, rest ...int) int {
+		for _, n := range rest {
+			first += n
+		}
+		return first
+	})
+	wrap(t, luaVm, "opt", func(p *int, v any) bool { return p == nil && v == nil })
+	wrap(t, luaVm, "pair", func(k string, v int) map[string]int { return map[string]int{k: v} })
+
+	rets := exec(t, luaVm, `return rep("ab", 3), sum(1, 2, 3), opt(), pair("a", 1).a`)
+	if got := decode[string](t, luaVm, rets[0]); got != "ababab" {
+		t.Errorf("rep = %q, want ababab", got)
+	}
+	if got := decode[int](t, luaVm, rets[1]); got != 6 {
+		t.Errorf("sum = %d, want 6", got)
+	}
+	if got := decode[bool](t, luaVm, rets[2]); !got {
+		t.Errorf("opt() = false, want nil pointer and interface")
+	}
+	if got := decode[int](t, luaVm, rets[3]); got != 1 {
+		t.Errorf(`pair("a", 1).a = %d, want 1`, got)
+	}
+}
+
+// repeatString is a named function for testing the function
+// names used in the argument errors of wrapped functions
+func repeatString(s string, n int) string {
+	return strings.Repeat(s, n)
+}
+
+func TestWrapFuncArgErrors(t *testing.T) {
+	luaVm := newVm(t)
+	wrap(t, luaVm, "rep", repeatString)
+	wrap(t, luaVm, "small", func(n uint8) uint8 { return n })
+	wrap(t, luaVm, "sum", func(rest ...int) int { return len(rest) })
+
+	for _, tc := range []struct {
+		code string
+		pos  int
+		msg  string
+	}{
+		{`rep("a")`, 2, "bad argument #2 to 'repeatString' (number expected, got nil)"},
+		{`rep(1, 2)`, 1, "bad argument #1 to 'repeatString' (string expected, got number)"},
+		{`rep("a", "b")`, 2, "bad argument #2 to 'repeatString' (number expected, got string)"},
+		{`rep("a", 1.5)`, 2, "bad argument #2 to 'repeatString' (number 1.5 is not an integer)"},
+		{`small(300)`, 1, "bad argument #1 (integer 300 overflows uint8)"},
+		{`sum(1, {}, 3)`, 2, "bad argument #2 (number expected, got table)"},
+	} {
+		_, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: tc.code})
+		var argErr *vm.ArgError
+		if !errors.As(err, &argErr) {
+			t.Errorf("%s: err = %v, want *ArgError", tc.code, err)
+			continue
+		}
+		if argErr.Pos != tc.pos || argErr.Error() != tc.msg {
+			t.Errorf("%s: err = %q (#%d), want %q", tc.code, argErr.Error(), argErr.Pos, tc.msg)
+		}
+	}
+}
+
+func TestWrapFuncSpecialParams(t *testing.T) {
+	luaVm := newVm(t)
+	errFailed := errors.New("failed")
+	wrap(t, luaVm, "check", func(v *vm.GoLuaVmWrapper, ok bool) (int, error) {
+		if v == nil {
+			return 0, errors.New("no callback VM")
+		}
+		if !ok {
+			return 0, errFailed
+		}
+		return 1, nil
+	})
+
+	rets := exec(t, luaVm, `return check(true)`)
+	if got := decode[int](t, luaVm, rets[0]); got != 1 {
+		t.Errorf("check(true) = %d, want 1", got)
+	}
+	if _, err := luaVm.ExecChunk(vm.ChunkOpts{Name: "test", Code: `check(false)`}); !errors.Is(err, errFailed) {
+		t.Errorf("check(false) = %v, want errFailed", err)
+	}
+}